@@ -0,0 +1,26 @@
+package v1
+
+// BuildInfo describes the running binary, as recorded by the Go toolchain.
+type BuildInfo struct {
+	GoVersion    string `json:"go_version"`
+	Module       string `json:"module"`
+	Version      string `json:"version"`
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revision_time,omitempty"`
+	Modified     bool   `json:"modified"`
+}
+
+// LogLevelRequest changes the log level, to a name such as "debug" or an
+// offset such as "info+2". Setting Package changes the level of that
+// package only, and an empty Level then makes it follow the root level
+// again.
+type LogLevelRequest struct {
+	Level   string `json:"level"`
+	Package string `json:"package,omitempty"`
+}
+
+// LogLevelResponse holds the root level and the packages overriding it.
+type LogLevelResponse struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListAuditRequest filters the audit trail. Empty fields match everything;
+// From is inclusive and To exclusive.
+type ListAuditRequest struct {
+	ActorIP    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditChange is the value of a field before and after a change. Sensitive
+// values are masked.
+type AuditChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type AuditRecord struct {
+	Id         uuid.UUID              `json:"id"`
+	ActorIP    string                 `json:"actor_ip"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	Changes    map[string]AuditChange `json:"changes"`
+	RequestID  string                 `json:"request_id"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+type ListAuditResponse struct {
+	Records []AuditRecord `json:"records"`
+	Total   int64         `json:"total"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
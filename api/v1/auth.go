@@ -0,0 +1,12 @@
+package v1
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// UnlockRequest clears the lockout of an account, a client address, or both.
+type UnlockRequest struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
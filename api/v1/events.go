@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of the domain events published about users.
+const (
+	EventUserCreated = "user.created"
+	EventUserUpdated = "user.updated"
+	EventUserDeleted = "user.deleted"
+)
+
+type UserCreatedEvent struct {
+	Id        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserUpdatedEvent carries the user after the update. Changed lists the
+// fields that changed.
+type UserUpdatedEvent struct {
+	Id        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Version   uint      `json:"version"`
+	Changed   []string  `json:"changed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserDeletedEvent struct {
+	Id        uuid.UUID `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
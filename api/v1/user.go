@@ -1,5 +1,7 @@
 package v1
 
+import "github.com/google/uuid"
+
 type CreateUserRequest struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
@@ -9,13 +11,40 @@ type GetUserByEmailRequest struct {
 	Email string `json:"email"`
 }
 
+type GetUserByIDRequest struct {
+	Id uuid.UUID `json:"-"`
+}
+
 type GetUserResponse struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	// Version is sent as the ETag header rather than in the body.
+	Version uint `json:"-"`
 }
 
 type UpdateUserRequest struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Version is the version the client expects to overwrite, taken from
+	// the If-Match header. Zero skips the precondition check.
+	Version uint `json:"-"`
+}
+
+// DeleteUserRequest deletes a user. Version is the version expected by
+// If-Match, which the handler requires; zero skips the check.
+type DeleteUserRequest struct {
+	Id      uuid.UUID
+	Version uint
+}
+
+// PatchUserRequest changes the fields that are set and leaves the others
+// as they are.
+type PatchUserRequest struct {
+	Id    uuid.UUID
+	Name  *string
+	Email *string
+	// Version is the version the changes were resolved against. Zero skips
+	// the precondition check.
+	Version uint
 }
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookPayload is the body posted to webhooks. Id is the id of the event,
+// which is the same for every webhook and every delivery of the event, and
+// Data its event specific payload, such as a UserCreatedEvent.
+type WebhookPayload struct {
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// CreateWebhookRequest registers a webhook. A secret is generated when
+// Secret is empty. EventTypes may contain "*" to receive every event.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+// UpdateWebhookRequest replaces the settings of a webhook. The secret is
+// kept.
+type UpdateWebhookRequest struct {
+	Id         uuid.UUID `json:"-"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+}
+
+// Webhook describes a registered webhook. Secret is only ever filled in
+// the response to its creation.
+type Webhook struct {
+	Id         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ListWebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// ListWebhookDeliveriesRequest pages through the deliveries of a webhook,
+// optionally only those with Status.
+type ListWebhookDeliveriesRequest struct {
+	WebhookId uuid.UUID
+	Status    string
+	Limit     int
+	Offset    int
+}
+
+type WebhookDelivery struct {
+	Id             uuid.UUID  `json:"id"`
+	WebhookId      uuid.UUID  `json:"webhook_id"`
+	EventId        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int64             `json:"total"`
+	Limit      int               `json:"limit"`
+	Offset     int               `json:"offset"`
+}
+
+// WebhookAttempt is one request made for a delivery. StatusCode is zero
+// when the endpoint did not answer.
+type WebhookAttempt struct {
+	Attempt      int       `json:"attempt"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryDetail is a delivery with the body it posts and the
+// history of its attempts, oldest first.
+type WebhookDeliveryDetail struct {
+	WebhookDelivery
+	Payload json.RawMessage  `json:"payload"`
+	History []WebhookAttempt `json:"history"`
+}
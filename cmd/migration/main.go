@@ -8,7 +8,9 @@ import (
 
 	"github.com/giortzisg/go-boilerplate/internal/repository"
 	"github.com/giortzisg/go-boilerplate/pkg/config"
+	"github.com/giortzisg/go-boilerplate/pkg/logging"
 	"github.com/giortzisg/go-boilerplate/pkg/migration"
+	"github.com/giortzisg/go-boilerplate/pkg/server"
 )
 
 func main() {
@@ -22,16 +24,18 @@ func main() {
 		os.Exit(1)
 	}
 
-	db := repository.NewDB(conf, logger)
-	migrateServer := migration.NewMigrateServer(db, logger)
-
-	defer func() {
-		if err := migrateServer.Stop(context.Background()); err != nil {
-			panic(err)
-		}
-	}()
+	loggers, err := logging.New(config.Logging(conf))
+	if err != nil {
+		logger.Error("error setting up logging", "error", err)
+		os.Exit(1)
+	}
+	defer loggers.Close()
+	logger = loggers.Logger()
 
-	if err := migrateServer.Start(context.Background()); err != nil {
-		panic(err)
+	db := repository.NewDB(conf, loggers.Named("gorm"))
+	runner := server.NewRunner(logger, server.WithServer("migration", migration.NewMigrateServer(db, logger)))
+	if err := runner.Run(context.Background()); err != nil {
+		logger.Error("migration failed", "error", err)
+		os.Exit(1)
 	}
 }
@@ -2,16 +2,35 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/giortzisg/go-boilerplate/internal/app"
 	"github.com/giortzisg/go-boilerplate/internal/handlers"
 	routerHttp "github.com/giortzisg/go-boilerplate/internal/http"
+	"github.com/giortzisg/go-boilerplate/internal/middleware"
 	"github.com/giortzisg/go-boilerplate/internal/repository"
 	"github.com/giortzisg/go-boilerplate/pkg/config"
+	"github.com/giortzisg/go-boilerplate/pkg/correlation"
+	"github.com/giortzisg/go-boilerplate/pkg/events"
+	"github.com/giortzisg/go-boilerplate/pkg/health"
+	"github.com/giortzisg/go-boilerplate/pkg/idempotency"
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+	"github.com/giortzisg/go-boilerplate/pkg/logging"
+	"github.com/giortzisg/go-boilerplate/pkg/ratelimit"
+	"github.com/giortzisg/go-boilerplate/pkg/server"
 	"github.com/giortzisg/go-boilerplate/pkg/server/http"
+	"github.com/giortzisg/go-boilerplate/pkg/telemetry"
+	"github.com/giortzisg/go-boilerplate/pkg/webhook"
+	"github.com/spf13/viper"
 )
 
 func main() {
@@ -25,21 +44,456 @@ func main() {
 		os.Exit(1)
 	}
 
-	sqlDB := repository.NewDB(conf, logger)
-	repo := repository.NewRepository(logger, sqlDB)
+	loggers, err := logging.New(config.Logging(conf), logging.WithHandler(func(h slog.Handler) slog.Handler {
+		return correlation.NewHandler(h)
+	}))
+	if err != nil {
+		logger.Error("error setting up logging", "error", err)
+		os.Exit(1)
+	}
+	logger = loggers.Logger()
+	// only the log levels take effect without a restart
+	conf.OnConfigChange(func(fsnotify.Event) {
+		if err := loggers.Configure(config.Logging(conf)); err != nil {
+			logger.Error("error reloading log config", "error", err)
+			return
+		}
+		logger.Info("reloaded log config")
+	})
+	conf.WatchConfig()
+
+	tracingConfig := telemetry.Config{
+		ServiceName: "go-boilerplate",
+		Exporter:    conf.GetString("tracing.exporter"),
+		Endpoint:    conf.GetString("tracing.endpoint"),
+		Insecure:    conf.GetBool("tracing.insecure"),
+		SampleRatio: 1,
+	}
+	if conf.IsSet("tracing.sample_ratio") {
+		tracingConfig.SampleRatio = conf.GetFloat64("tracing.sample_ratio")
+	}
+	tracerProvider, err := telemetry.NewTracerProvider(context.Background(), tracingConfig)
+	if err != nil {
+		logger.Error("error setting up tracing", "error", err)
+		os.Exit(1)
+	}
+	telemetry.SetGlobal(tracerProvider)
+
+	sqlDB := repository.NewDB(conf, loggers.Named("gorm"))
+	repo := repository.NewRepository(loggers.Named("repository"), sqlDB)
 	userRepo := repository.NewUserRepository(repo)
-	userService := app.NewUserService(userRepo)
-	userHandler := handlers.NewUserHandler(handlers.NewHandler(logger), userService)
-	router := routerHttp.NewRouter(logger, *userHandler)
-
-	s := http.NewServer(
-		router.Mux,
-		logger,
-		http.WithHost("localhost"),
-		http.WithPort(8080),
+	auditRepo := repository.NewAuditRepository(repo)
+	userService := app.NewUserService(userRepo, auditRepo, repository.NewOutboxRepository(repo), repository.NewTransaction(repo))
+
+	decodeOptions := []json.DecodeOption{json.WithStrict(conf.GetBool("http.request.strict"))}
+	if conf.IsSet("http.request.max_body_size") {
+		decodeOptions = append(decodeOptions, json.WithMaxBodySize(conf.GetInt64("http.request.max_body_size")))
+	}
+	handler := handlers.NewHandler(loggers.Named("handlers"), decodeOptions...)
+	userHandler := handlers.NewUserHandler(handler, userService)
+	webhookRepo := repository.NewWebhookRepository(repo)
+
+	trustedProxies, err := middleware.ParseTrustedProxies(conf.GetStringSlice("http.access_log.trusted_proxies"))
+	if err != nil {
+		logger.Error("error loading config", "error", err)
+		os.Exit(1)
+	}
+
+	lockoutPolicy := app.DefaultLockoutPolicy()
+	if conf.IsSet("auth.lockout") {
+		lockoutPolicy = app.LockoutPolicy{
+			MaxAccountFailures: conf.GetInt("auth.lockout.max_account_failures"),
+			MaxIPFailures:      conf.GetInt("auth.lockout.max_ip_failures"),
+			LockoutDuration:    conf.GetDuration("auth.lockout.duration"),
+			BaseDelay:          conf.GetDuration("auth.lockout.base_delay"),
+			MaxDelay:           conf.GetDuration("auth.lockout.max_delay"),
+			FailureWindow:      conf.GetDuration("auth.lockout.failure_window"),
+		}
+	}
+	authService := app.NewAuthService(loggers.Named("app"), userRepo, repository.NewLoginAttemptRepository(repo), auditRepo, repository.NewTransaction(repo), lockoutPolicy)
+	authHandler := handlers.NewAuthHandler(handler, authService, trustedProxies.ClientIP)
+	accessLogOptions := []middleware.LoggingOption{middleware.WithTrustedProxies(trustedProxies)}
+	if conf.IsSet("http.access_log.sample_rate") {
+		accessLogOptions = append(accessLogOptions, middleware.WithSampleRate(conf.GetFloat64("http.access_log.sample_rate")))
+	}
+	if conf.GetString("http.access_log.format") == "combined" {
+		accessLogOptions = append(accessLogOptions, middleware.WithCombinedLogFormat(loggers.Writer()))
+	}
+
+	healthRegistry := health.NewRegistry()
+	healthRegistry.AddReadinessCheck("database", repository.NewDBHealthCheck(sqlDB))
+
+	routerOptions := []routerHttp.Option{
+		routerHttp.WithAccessLog(accessLogOptions...),
+		routerHttp.WithHealth(healthRegistry),
+		routerHttp.WithAuth(authHandler),
+		routerHttp.WithAuditActor(trustedProxies),
+	}
+	if conf.GetBool("http.rate_limit.enabled") {
+		rateLimit := routerHttp.RateLimitConfig{
+			Store: ratelimit.NewMemoryStore(),
+			Default: ratelimit.Limit{
+				Requests: conf.GetInt("http.rate_limit.requests"),
+				Period:   conf.GetDuration("http.rate_limit.period"),
+				Burst:    conf.GetInt("http.rate_limit.burst"),
+			},
+		}
+		rateLimit.CreateUser = routeLimit(conf, "http.rate_limit.routes.create_user", ratelimit.PerMinute(10))
+		rateLimit.Login = routeLimit(conf, "http.rate_limit.routes.login", ratelimit.PerMinute(20))
+		if conf.GetString("http.rate_limit.store") == "sql" {
+			rateLimit.Store = repository.NewRateLimitStore(repo)
+		}
+		rateLimit.Key = clientKey(conf.GetString("http.rate_limit.key"), conf.GetString("http.rate_limit.api_key_header"), trustedProxies)
+		routerOptions = append(routerOptions, routerHttp.WithRateLimit(rateLimit))
+	}
+
+	if conf.GetBool("http.idempotency.enabled") {
+		idempotencyConfig := middleware.IdempotencyConfig{
+			Store:       idempotency.NewMemoryStore(),
+			Key:         clientKey(conf.GetString("http.idempotency.key"), conf.GetString("http.idempotency.api_key_header"), trustedProxies),
+			Lease:       conf.GetDuration("http.idempotency.lease"),
+			TTL:         conf.GetDuration("http.idempotency.ttl"),
+			MaxBodySize: conf.GetInt64("http.request.max_body_size"),
+		}
+		if conf.GetString("http.idempotency.store") == "sql" {
+			idempotencyConfig.Store = repository.NewIdempotencyStore(repo)
+		}
+		routerOptions = append(routerOptions, routerHttp.WithIdempotency(idempotencyConfig))
+	}
+
+	if conf.GetBool("http.compression.enabled") {
+		var compressOptions []middleware.CompressOption
+		if conf.IsSet("http.compression.min_size") {
+			compressOptions = append(compressOptions, middleware.WithMinSize(conf.GetInt("http.compression.min_size")))
+		}
+		if conf.IsSet("http.compression.content_types") {
+			compressOptions = append(compressOptions, middleware.WithCompressibleTypes(conf.GetStringSlice("http.compression.content_types")...))
+		}
+		if conf.IsSet("http.compression.encodings") {
+			compressOptions = append(compressOptions, middleware.WithEncodings(conf.GetStringSlice("http.compression.encodings")...))
+		}
+		routerOptions = append(routerOptions, routerHttp.WithCompression(compressOptions...))
+	}
+
+	if conf.GetBool("http.cors.enabled") {
+		cors := routerHttp.CORSConfig{
+			Default: corsConfig(conf, "http.cors", middleware.CORSConfig{}),
+			Routes:  map[string]middleware.CORSConfig{},
+		}
+		routes, err := routeEntries(conf, "http.cors.routes")
+		if err != nil {
+			logger.Error("error loading config", "error", err)
+			os.Exit(1)
+		}
+		for _, route := range routes {
+			cors.Routes[route.GetString("pattern")] = corsConfig(route, "", cors.Default)
+		}
+		routerOptions = append(routerOptions, routerHttp.WithCORS(cors))
+	}
+
+	if conf.IsSet("http.request_timeout") {
+		timeout := routerHttp.TimeoutConfig{
+			Default: conf.GetDuration("http.request_timeout.default"),
+			Routes:  map[string]time.Duration{},
+		}
+		routes, err := routeEntries(conf, "http.request_timeout.routes")
+		if err != nil {
+			logger.Error("error loading config", "error", err)
+			os.Exit(1)
+		}
+		for _, route := range routes {
+			timeout.Routes[route.GetString("pattern")] = route.GetDuration("timeout")
+		}
+		routerOptions = append(routerOptions, routerHttp.WithTimeout(timeout))
+	}
+
+	router := routerHttp.NewRouter(loggers.Named("http"), *userHandler, routerOptions...)
+	if err = router.Validate(); err != nil {
+		logger.Error("error loading config", "error", err)
+		os.Exit(1)
+	}
+
+	serverOptions := []http.Option{
+		http.WithHost(conf.GetString("http.host")),
+		http.WithPort(conf.GetInt("http.port")),
+		http.WithTracerProvider(tracerProvider),
+		http.WithOnShutdown(healthRegistry.Drain),
+	}
+	if conf.IsSet("http.shutdown.timeout") {
+		serverOptions = append(serverOptions, http.WithShutdownTimeout(conf.GetDuration("http.shutdown.timeout")))
+	}
+	if conf.IsSet("http.shutdown.delay") {
+		serverOptions = append(serverOptions, http.WithShutdownDelay(conf.GetDuration("http.shutdown.delay")))
+	}
+	listenOptions, err := serverListenOptions(conf)
+	if err != nil {
+		logger.Error("error setting up the listener", "error", err)
+		os.Exit(1)
+	}
+	serverOptions = append(serverOptions, listenOptions...)
+	if conf.IsSet("http.server.read_header_timeout") {
+		serverOptions = append(serverOptions, http.WithReadHeaderTimeout(conf.GetDuration("http.server.read_header_timeout")))
+	}
+	if conf.IsSet("http.server.read_timeout") {
+		serverOptions = append(serverOptions, http.WithReadTimeout(conf.GetDuration("http.server.read_timeout")))
+	}
+	if conf.IsSet("http.server.write_timeout") {
+		serverOptions = append(serverOptions, http.WithWriteTimeout(conf.GetDuration("http.server.write_timeout")))
+	}
+	if conf.IsSet("http.server.idle_timeout") {
+		serverOptions = append(serverOptions, http.WithIdleTimeout(conf.GetDuration("http.server.idle_timeout")))
+	}
+
+	if conf.GetBool("http.tls.enabled") {
+		tlsConfig, err := serverTLSConfig(conf)
+		if err != nil {
+			logger.Error("error loading config", "error", err)
+			os.Exit(1)
+		}
+		serverOptions = append(serverOptions, http.WithTLS(tlsConfig))
+	}
+
+	s := http.NewServer(router.Mux, loggers.Named("server"), serverOptions...)
+
+	adminOptions := []routerHttp.AdminOption{
+		routerHttp.WithAdminHandler(handlers.NewAdminHandler(handler, loggers, conf.AllSettings)),
+		routerHttp.WithAudit(handlers.NewAuditHandler(handler, app.NewAuditService(auditRepo))),
+		routerHttp.WithWebhooks(handlers.NewWebhookHandler(handler, app.NewWebhookService(webhookRepo))),
+		routerHttp.WithUsers(userHandler),
+	}
+	if conf.GetBool("admin.pprof") {
+		adminOptions = append(adminOptions, routerHttp.WithProfiling())
+	}
+	if conf.GetBool("admin.expvar") {
+		adminOptions = append(adminOptions, routerHttp.WithExpvar())
+	}
+	adminRouter := routerHttp.NewAdminRouter(loggers.Named("http"), authHandler, adminOptions...)
+	// the admin endpoints have no authentication of their own, so they are
+	// only reachable from the host unless configured otherwise
+	adminHost := "127.0.0.1"
+	if conf.IsSet("admin.host") {
+		adminHost = conf.GetString("admin.host")
+	}
+	admin := http.NewServer(
+		adminRouter.Mux,
+		loggers.Named("server"),
+		http.WithHost(adminHost),
+		http.WithPort(conf.GetInt("admin.port")),
 	)
 
-	if err := s.Start(context.Background()); err != nil {
+	runnerOptions := []server.Option{
+		server.WithServer("http", s),
+		server.WithServer("admin", admin),
+		server.WithCleanup("database", repository.NewDBCloser(sqlDB)),
+		server.WithCleanup("tracing", tracerProvider.Shutdown),
+		server.WithCleanup("logging", func(context.Context) error { return loggers.Close() }),
+	}
+	if conf.GetBool("events.enabled") {
+		eventLogger := loggers.Named("events")
+		dispatcher := events.NewDispatcher(eventLogger, repository.NewOutboxStore(repo), dispatcherOptions(conf, "events")...)
+		dispatcher.Subscribe(events.AllEvents, "log", func(ctx context.Context, event events.Event) error {
+			eventLogger.DebugContext(ctx, "Event published", "event_id", event.ID, "event_type", event.Type, "aggregate_id", event.AggregateID)
+			return nil
+		})
+		runnerOptions = append(runnerOptions, server.WithServer("events", dispatcher))
+
+		// webhooks get deliveries of their own, so that a failing endpoint
+		// is retried without holding up the other subscribers
+		if conf.GetBool("webhooks.enabled") {
+			dispatcher.Subscribe(events.AllEvents, "webhooks", app.NewWebhookFanOut(webhookRepo))
+			webhookLogger := loggers.Named("webhooks")
+			deliveries := events.NewDispatcher(webhookLogger, repository.NewWebhookDeliveryStore(repo), dispatcherOptions(conf, "webhooks")...)
+			deliveries.Subscribe(events.AllEvents, "http", app.NewWebhookSender(webhookLogger, webhookRepo, webhook.NewSender(webhookSenderOptions(conf)...)))
+			runnerOptions = append(runnerOptions, server.WithServer("webhooks", deliveries))
+		}
+	}
+	if conf.IsSet("lifecycle.stop_timeout") {
+		runnerOptions = append(runnerOptions, server.WithStopTimeout(conf.GetDuration("lifecycle.stop_timeout")))
+	}
+	if err := server.NewRunner(logger, runnerOptions...).Run(context.Background()); err != nil {
+		logger.Error("server stopped", "error", err)
 		os.Exit(1)
 	}
 }
+
+// serverListenOptions reads the http.listen settings, which replace host
+// and port with a Unix socket or a socket passed by systemd.
+func serverListenOptions(conf *viper.Viper) ([]http.Option, error) {
+	var opts []http.Option
+	if conf.GetBool("http.listen.h2c") {
+		opts = append(opts, http.WithH2C())
+	}
+
+	switch {
+	case conf.GetBool("http.listen.systemd"):
+		l, err := http.SystemdListener(conf.GetString("http.listen.systemd_name"))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, http.WithListener(l))
+	case conf.GetString("http.listen.unix_socket") != "":
+		var mode uint64
+		if value := conf.GetString("http.listen.unix_socket_mode"); value != "" {
+			var err error
+			if mode, err = strconv.ParseUint(value, 8, 32); err != nil {
+				return nil, fmt.Errorf("invalid unix socket mode %q: %w", value, err)
+			}
+		}
+		opts = append(opts, http.WithUnixSocket(conf.GetString("http.listen.unix_socket"), fs.FileMode(mode)))
+	}
+	return opts, nil
+}
+
+// dispatcherOptions reads the dispatcher settings under key, which is
+// "events" for the outbox and "webhooks" for webhook deliveries. Replicas
+// with events disabled still write to the outbox and leave delivery to the
+// others.
+func dispatcherOptions(conf *viper.Viper, key string) []events.Option {
+	get := func(name string) string { return key + "." + name }
+
+	var opts []events.Option
+	if conf.IsSet(get("poll_interval")) {
+		opts = append(opts, events.WithPollInterval(conf.GetDuration(get("poll_interval"))))
+	}
+	if conf.IsSet(get("batch_size")) {
+		opts = append(opts, events.WithBatchSize(conf.GetInt(get("batch_size"))))
+	}
+	if conf.IsSet(get("max_attempts")) {
+		opts = append(opts, events.WithMaxAttempts(conf.GetInt(get("max_attempts"))))
+	}
+	if conf.IsSet(get("retry_backoff")) {
+		maxBackoff := events.DefaultMaxRetryBackoff
+		if conf.IsSet(get("max_retry_backoff")) {
+			maxBackoff = conf.GetDuration(get("max_retry_backoff"))
+		}
+		opts = append(opts, events.WithRetryBackoff(conf.GetDuration(get("retry_backoff")), maxBackoff))
+	}
+	if conf.IsSet(get("lease")) {
+		opts = append(opts, events.WithLease(conf.GetDuration(get("lease"))))
+	}
+	return opts
+}
+
+// webhookSenderOptions reads the webhooks settings of the HTTP requests.
+func webhookSenderOptions(conf *viper.Viper) []webhook.Option {
+	var opts []webhook.Option
+	if conf.IsSet("webhooks.timeout") {
+		opts = append(opts, webhook.WithTimeout(conf.GetDuration("webhooks.timeout")))
+	}
+	if conf.IsSet("webhooks.user_agent") {
+		opts = append(opts, webhook.WithUserAgent(conf.GetString("webhooks.user_agent")))
+	}
+	return opts
+}
+
+// serverTLSConfig reads the http.tls settings.
+func serverTLSConfig(conf *viper.Viper) (http.TLSConfig, error) {
+	tlsConfig := http.TLSConfig{
+		CertFile:     conf.GetString("http.tls.cert_file"),
+		KeyFile:      conf.GetString("http.tls.key_file"),
+		ClientCAFile: conf.GetString("http.tls.client_ca_file"),
+	}
+
+	var err error
+	if tlsConfig.MinVersion, err = http.ParseTLSVersion(conf.GetString("http.tls.min_version")); err != nil {
+		return tlsConfig, err
+	}
+	if tlsConfig.CipherSuites, err = http.ParseCipherSuites(conf.GetStringSlice("http.tls.cipher_suites")); err != nil {
+		return tlsConfig, err
+	}
+	if tlsConfig.ClientCAFile != "" {
+		if tlsConfig.ClientAuth, err = http.ParseClientAuth(conf.GetString("http.tls.client_auth")); err != nil {
+			return tlsConfig, err
+		}
+		// the server would read NoClientCert as unset and require certificates
+		if tlsConfig.ClientAuth == tls.NoClientCert {
+			return tlsConfig, errors.New("http.tls.client_auth none cannot be combined with client_ca_file, remove client_ca_file to turn off mutual TLS")
+		}
+	}
+	return tlsConfig, nil
+}
+
+// routeLimit reads the rate limit of a route under key, keeping the value
+// from base for every setting that is not present.
+func routeLimit(conf *viper.Viper, key string, base ratelimit.Limit) ratelimit.Limit {
+	if conf.IsSet(key + ".requests") {
+		base.Requests = conf.GetInt(key + ".requests")
+	}
+	if conf.IsSet(key + ".period") {
+		base.Period = conf.GetDuration(key + ".period")
+	}
+	if conf.IsSet(key + ".burst") {
+		base.Burst = conf.GetInt(key + ".burst")
+	}
+	return base
+}
+
+// clientKey returns the KeyFunc named in the config: "ip" or "api_key",
+// which reads the key from apiKeyHeader.
+func clientKey(name, apiKeyHeader string, trustedProxies middleware.TrustedProxies) middleware.KeyFunc {
+	switch name {
+	case "api_key":
+		return middleware.KeyByAPIKey(apiKeyHeader, trustedProxies)
+	}
+	return middleware.KeyByIP(trustedProxies)
+}
+
+// routeEntries reads the list of per-route settings under key. Entries are
+// a list rather than a map keyed by pattern, since viper lower cases keys
+// and splits them on dots. Each entry names its chi pattern in "pattern".
+func routeEntries(conf *viper.Viper, key string) ([]*viper.Viper, error) {
+	var entries []map[string]any
+	if err := conf.UnmarshalKey(key, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+
+	routes := make([]*viper.Viper, 0, len(entries))
+	seen := map[string]bool{}
+	for i, entry := range entries {
+		route := viper.New()
+		if err := route.MergeConfigMap(entry); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", key, i, err)
+		}
+		pattern := route.GetString("pattern")
+		if pattern == "" {
+			return nil, fmt.Errorf("%s[%d]: pattern is required", key, i)
+		}
+		if seen[pattern] {
+			return nil, fmt.Errorf("%s[%d]: duplicate pattern %q", key, i, pattern)
+		}
+		seen[pattern] = true
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// corsConfig reads the CORS settings under key, keeping the value from base
+// for every setting that is not present.
+func corsConfig(conf *viper.Viper, key string, base middleware.CORSConfig) middleware.CORSConfig {
+	get := func(name string) string {
+		if key == "" {
+			return name
+		}
+		return key + "." + name
+	}
+
+	if conf.IsSet(get("allowed_origins")) {
+		base.AllowedOrigins = conf.GetStringSlice(get("allowed_origins"))
+	}
+	if conf.IsSet(get("allowed_methods")) {
+		base.AllowedMethods = conf.GetStringSlice(get("allowed_methods"))
+	}
+	if conf.IsSet(get("allowed_headers")) {
+		base.AllowedHeaders = conf.GetStringSlice(get("allowed_headers"))
+	}
+	if conf.IsSet(get("exposed_headers")) {
+		base.ExposedHeaders = conf.GetStringSlice(get("exposed_headers"))
+	}
+	if conf.IsSet(get("allow_credentials")) {
+		base.AllowCredentials = conf.GetBool(get("allow_credentials"))
+	}
+	if conf.IsSet(get("max_age")) {
+		base.MaxAge = conf.GetDuration(get("max_age"))
+	}
+	return base
+}
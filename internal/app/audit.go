@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/internal/repository"
+	"github.com/giortzisg/go-boilerplate/pkg/audit"
+	"github.com/giortzisg/go-boilerplate/pkg/correlation"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+	"github.com/google/uuid"
+)
+
+// Audited actions.
+const (
+	AuditUserCreate   = "user.create"
+	AuditUserUpdate   = "user.update"
+	AuditUserDelete   = "user.delete"
+	AuditLoginLockout = "login.lockout"
+	AuditLoginUnlock  = "login.unlock"
+)
+
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 500
+)
+
+var ErrInvalidAuditQuery = e.NewStatusError(errors.New("invalid audit query"), http.StatusBadRequest)
+
+type AuditService interface {
+	List(ctx context.Context, req *v1.ListAuditRequest) (*v1.ListAuditResponse, error)
+}
+
+func NewAuditService(auditRepository repository.AuditRepository) AuditService {
+	return &auditService{
+		auditRepo: auditRepository,
+	}
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func (s *auditService) List(ctx context.Context, req *v1.ListAuditRequest) (_ *v1.ListAuditResponse, err error) {
+	ctx, span := tracer.Start(ctx, "auditService.List")
+	defer func() { endSpan(span, err) }()
+
+	limit := req.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultAuditPageSize
+	case limit < 0, limit > MaxAuditPageSize:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidAuditQuery, MaxAuditPageSize)
+	}
+	if req.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidAuditQuery)
+	}
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidAuditQuery)
+	}
+
+	records, total, err := s.auditRepo.List(ctx, repository.AuditFilter{
+		ActorIP:    req.ActorIP,
+		Action:     req.Action,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		From:       req.From,
+		To:         req.To,
+		Limit:      limit,
+		Offset:     req.Offset,
+	})
+	if err != nil {
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	response := &v1.ListAuditResponse{
+		Records: make([]v1.AuditRecord, 0, len(records)),
+		Total:   total,
+		Limit:   limit,
+		Offset:  req.Offset,
+	}
+	for _, record := range records {
+		changes := map[string]v1.AuditChange{}
+		if record.Changes != "" {
+			if err = stdjson.Unmarshal([]byte(record.Changes), &changes); err != nil {
+				return nil, e.NewStatusError(fmt.Errorf("decoding changes of audit record %s: %w", record.Id, err), http.StatusInternalServerError)
+			}
+		}
+		response.Records = append(response.Records, v1.AuditRecord{
+			Id:         record.Id,
+			ActorIP:    record.ActorIP,
+			Action:     record.Action,
+			TargetType: record.TargetType,
+			TargetID:   record.TargetID,
+			Changes:    changes,
+			RequestID:  record.RequestID,
+			CreatedAt:  record.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+// auditLog writes audit records. Callers write them in the transaction of
+// the change they describe, so one is never committed without the other.
+type auditLog struct {
+	repo repository.AuditRepository
+	now  func() time.Time
+}
+
+// record stores the change of a target between the before and after
+// snapshots, masking the values of the sensitive fields.
+func (a *auditLog) record(ctx context.Context, action, targetType, targetID string, before, after map[string]any, sensitive ...string) error {
+	changes, err := stdjson.Marshal(audit.Diff(before, after, sensitive...))
+	if err != nil {
+		return err
+	}
+
+	actor := audit.ActorFromContext(ctx)
+	return a.repo.Create(ctx, &model.AuditRecord{
+		Id:         uuid.New(),
+		ActorIP:    actor.IP,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    string(changes),
+		RequestID:  correlation.RequestID(ctx),
+		CreatedAt:  a.now(),
+	})
+}
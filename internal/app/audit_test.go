@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/internal/repository"
+	"github.com/giortzisg/go-boilerplate/pkg/audit"
+	"github.com/giortzisg/go-boilerplate/pkg/correlation"
+	mock_repository "github.com/giortzisg/go-boilerplate/test/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newUserServiceWithMocks returns a UserService whose transactions run fn
+// inline, and the audit repository it writes to. Its events are accepted
+// without checks.
+func newUserServiceWithMocks(ctrl *gomock.Controller, userRepo repository.UserRepository) (UserService, *mock_repository.MockAuditRepository) {
+	u, auditRepo, outboxRepo := newUserServiceWithOutbox(ctrl, userRepo)
+	outboxRepo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return u, auditRepo
+}
+
+// newUserServiceWithOutbox is newUserServiceWithMocks that also returns the
+// outbox repository, leaving the expected events to the caller.
+func newUserServiceWithOutbox(ctrl *gomock.Controller, userRepo repository.UserRepository) (UserService, *mock_repository.MockAuditRepository, *mock_repository.MockOutboxRepository) {
+	auditRepo := mock_repository.NewMockAuditRepository(ctrl)
+	outboxRepo := mock_repository.NewMockOutboxRepository(ctrl)
+	return NewUserService(userRepo, auditRepo, outboxRepo, passthroughTransaction(ctrl)), auditRepo, outboxRepo
+}
+
+// passthroughTransaction runs transactions directly on the context given.
+func passthroughTransaction(ctrl *gomock.Controller) *mock_repository.MockTransaction {
+	tx := mock_repository.NewMockTransaction(ctrl)
+	tx.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	}).AnyTimes()
+	return tx
+}
+
+type auditRecordMatcher struct{ action string }
+
+func (m auditRecordMatcher) Matches(x interface{}) bool {
+	record, ok := x.(*model.AuditRecord)
+	targetType, _, _ := strings.Cut(m.action, ".")
+	return ok && record.Action == m.action && record.TargetType == targetType
+}
+
+func (m auditRecordMatcher) String() string {
+	return fmt.Sprintf("is a %s audit record", m.action)
+}
+
+func auditRecordOf(action string) gomock.Matcher {
+	return auditRecordMatcher{action: action}
+}
+
+func Test_userService_AuditRecord(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepo := mock_repository.NewMockUserRepository(ctrl)
+	u, auditRepo := newUserServiceWithMocks(ctrl, userRepo)
+
+	ctx := correlation.WithRequestID(context.Background(), "req-1")
+	ctx = audit.WithActor(ctx, audit.Actor{IP: "192.0.2.1"})
+
+	var record *model.AuditRecord
+	userRepo.EXPECT().GetByEmail(gomock.Any(), "ann@example.com").Return(nil, nil)
+	userRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r *model.AuditRecord) error {
+		record = r
+		return nil
+	})
+
+	err := u.Create(ctx, &v1.CreateUserRequest{Name: "Ann", Email: "ann@example.com", Password: "hunter2"})
+	assert.NoError(t, err)
+	assert.Equal(t, AuditUserCreate, record.Action)
+	assert.Equal(t, "192.0.2.1", record.ActorIP)
+	assert.Equal(t, "req-1", record.RequestID)
+
+	var changes map[string]audit.Change
+	assert.NoError(t, json.Unmarshal([]byte(record.Changes), &changes))
+	assert.Equal(t, audit.Change{To: "Ann"}, changes["name"])
+	assert.Equal(t, audit.Change{To: audit.Masked}, changes["password"])
+	assert.NotContains(t, record.Changes, "hunter2")
+}
+
+func Test_userService_AuditFailureFailsTheChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepo := mock_repository.NewMockUserRepository(ctrl)
+	u, auditRepo := newUserServiceWithMocks(ctrl, userRepo)
+
+	userRepo.EXPECT().GetByEmail(gomock.Any(), "ann@example.com").Return(&model.User{Id: uuid.New(), Name: "Ann", Email: "ann@example.com", Version: 1}, nil)
+	userRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	errAudit := errors.New("audit table unavailable")
+	auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserUpdate)).Return(errAudit)
+
+	_, err := u.Update(context.Background(), &v1.UpdateUserRequest{Name: "Anna", Email: "ann@example.com"})
+	assert.ErrorIs(t, err, errAudit)
+}
+
+func Test_auditService_List(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	auditRepo := mock_repository.NewMockAuditRepository(ctrl)
+	s := NewAuditService(auditRepo)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	auditRepo.EXPECT().List(gomock.Any(), repository.AuditFilter{
+		Action: AuditUserUpdate,
+		From:   from,
+		Limit:  DefaultAuditPageSize,
+		Offset: 10,
+	}).Return([]model.AuditRecord{{
+		Id:         id,
+		Action:     AuditUserUpdate,
+		TargetType: "user",
+		Changes:    `{"name":{"from":"Ann","to":"Anna"}}`,
+	}}, int64(11), nil)
+
+	got, err := s.List(context.Background(), &v1.ListAuditRequest{Action: AuditUserUpdate, From: from, Offset: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), got.Total)
+	assert.Equal(t, DefaultAuditPageSize, got.Limit)
+	assert.Len(t, got.Records, 1)
+	assert.Equal(t, id, got.Records[0].Id)
+	assert.Equal(t, v1.AuditChange{From: "Ann", To: "Anna"}, got.Records[0].Changes["name"])
+
+	for _, req := range []*v1.ListAuditRequest{
+		{Limit: MaxAuditPageSize + 1},
+		{Offset: -1},
+		{From: from, To: from},
+	} {
+		_, err := s.List(context.Background(), req)
+		assert.ErrorIs(t, err, ErrInvalidAuditQuery)
+	}
+}
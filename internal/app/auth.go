@@ -0,0 +1,292 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/internal/repository"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials = e.NewStatusError(errors.New("invalid email or password"), http.StatusUnauthorized)
+	ErrLoginThrottled     = errors.New("too many failed login attempts")
+	ErrNothingToUnlock    = e.NewStatusError(errors.New("email or ip is required"), http.StatusBadRequest)
+)
+
+// LockoutPolicy controls how failed logins are throttled. Every failure
+// delays the next attempt by BaseDelay, doubling per failure up to MaxDelay,
+// and reaching a threshold locks the subject for LockoutDuration. Failures
+// older than FailureWindow are forgotten.
+type LockoutPolicy struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	LockoutDuration    time.Duration
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	FailureWindow      time.Duration
+}
+
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		LockoutDuration:    15 * time.Minute,
+		BaseDelay:          time.Second,
+		MaxDelay:           30 * time.Second,
+		FailureWindow:      time.Hour,
+	}
+}
+
+// delay returns how long a subject with failures must wait between attempts.
+func (p LockoutPolicy) delay(failures int) time.Duration {
+	if failures <= 0 || p.BaseDelay <= 0 {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := 1; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+type AuthService interface {
+	Login(ctx context.Context, req *v1.LoginRequest, ip string) (*v1.GetUserResponse, error)
+	Unlock(ctx context.Context, req *v1.UnlockRequest) error
+}
+
+// NewAuthService creates an AuthService. Each login is checked and counted
+// in transactions of tx, and lockouts are written to the audit trail.
+func NewAuthService(
+	logger *slog.Logger,
+	userRepository repository.UserRepository,
+	attemptRepository repository.LoginAttemptRepository,
+	auditRepository repository.AuditRepository,
+	tx repository.Transaction,
+	policy LockoutPolicy,
+) AuthService {
+	return &authService{
+		logger:      logger,
+		userRepo:    userRepository,
+		attemptRepo: attemptRepository,
+		audit:       &auditLog{repo: auditRepository, now: time.Now},
+		tx:          tx,
+		policy:      policy,
+		now:         time.Now,
+	}
+}
+
+type authService struct {
+	logger      *slog.Logger
+	userRepo    repository.UserRepository
+	attemptRepo repository.LoginAttemptRepository
+	audit       *auditLog
+	tx          repository.Transaction
+	policy      LockoutPolicy
+	now         func() time.Time
+}
+
+// dummyHash is compared against when the account does not exist, so that
+// unknown emails take as long to reject as wrong passwords.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (a *authService) Login(ctx context.Context, req *v1.LoginRequest, ip string) (_ *v1.GetUserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "authService.Login")
+	defer func() { endSpan(span, err) }()
+
+	if err = a.attemptRepo.Purge(ctx, a.now().Add(-a.policy.FailureWindow)); err != nil {
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	keys := []string{accountKey(req.Email), ipKey(ip)}
+	thresholds := []int{a.policy.MaxAccountFailures, a.policy.MaxIPFailures}
+
+	// the password is compared outside of the row locks, so that a slow
+	// hash does not hold up the other logins of the subject; the throttle
+	// is checked again once the outcome is recorded under the locks
+	if err = a.tx.Transaction(ctx, func(ctx context.Context) error {
+		_, err := a.acquire(ctx, keys)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	user, err := a.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+	hash := dummyHash()
+	if user != nil {
+		hash = []byte(user.Password)
+	}
+	valid := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) == nil && user != nil
+
+	err = a.tx.Transaction(ctx, func(ctx context.Context) error {
+		attempts, err := a.acquire(ctx, keys)
+		if err != nil {
+			return err
+		}
+
+		if !valid {
+			for i, attempt := range attempts {
+				if err = a.recordFailure(ctx, attempt, thresholds[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if err = a.attemptRepo.Reset(ctx, keys[0]); err != nil {
+			return e.NewStatusError(err, http.StatusInternalServerError)
+		}
+		// the address keeps its failures, it may be shared with others
+		if ipAttempt := attempts[1]; ipAttempt.Failures == 0 {
+			err = a.attemptRepo.Reset(ctx, ipAttempt.SubjectKey)
+		} else {
+			err = a.attemptRepo.Save(ctx, ipAttempt)
+		}
+		if err != nil {
+			return e.NewStatusError(err, http.StatusInternalServerError)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// a rejected password is only returned once its failure has committed
+	if !valid {
+		return nil, ErrInvalidCredentials
+	}
+	return &v1.GetUserResponse{
+		Name:    user.Name,
+		Email:   user.Email,
+		Version: user.Version,
+	}, nil
+}
+
+// acquire locks the counters of keys and checks that none of them is
+// throttled.
+func (a *authService) acquire(ctx context.Context, keys []string) ([]*model.LoginAttempt, error) {
+	attempts := make([]*model.LoginAttempt, len(keys))
+	for i, key := range keys {
+		attempt, err := a.attemptRepo.Acquire(ctx, key)
+		if err != nil {
+			return nil, e.NewStatusError(err, http.StatusInternalServerError)
+		}
+		if err = a.checkThrottle(attempt); err != nil {
+			return nil, err
+		}
+		attempts[i] = attempt
+	}
+	return attempts, nil
+}
+
+// checkThrottle rejects the attempt while the subject is locked or still
+// within the progressive delay that followed its last failure. An expired
+// lock is lifted along with the failures that led to it.
+func (a *authService) checkThrottle(attempt *model.LoginAttempt) error {
+	now := a.now()
+	if attempt.LockedUntil != nil {
+		if now.Before(*attempt.LockedUntil) {
+			return e.NewRetryError(ErrLoginThrottled, http.StatusTooManyRequests, attempt.LockedUntil.Sub(now))
+		}
+		attempt.LockedUntil = nil
+		attempt.Failures = 0
+	}
+	if attempt.Failures == 0 || now.Sub(attempt.LastFailureAt) > a.policy.FailureWindow {
+		return nil
+	}
+	if next := attempt.LastFailureAt.Add(a.policy.delay(attempt.Failures)); now.Before(next) {
+		return e.NewRetryError(ErrLoginThrottled, http.StatusTooManyRequests, next.Sub(now))
+	}
+	return nil
+}
+
+// recordFailure counts one more failure of the subject, forgetting those
+// older than the failure window. Reaching threshold locks the subject and
+// starts counting again from zero.
+func (a *authService) recordFailure(ctx context.Context, attempt *model.LoginAttempt, threshold int) error {
+	now := a.now()
+	if now.Sub(attempt.LastFailureAt) > a.policy.FailureWindow {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+
+	if threshold > 0 && attempt.Failures >= threshold {
+		failures := attempt.Failures
+		until := now.Add(a.policy.LockoutDuration)
+		attempt.Failures = 0
+		attempt.LockedUntil = &until
+
+		if err := a.audit.record(ctx, AuditLoginLockout, "login", attempt.SubjectKey, nil, map[string]any{
+			"failures":     failures,
+			"locked_until": until,
+		}); err != nil {
+			return e.NewStatusError(err, http.StatusInternalServerError)
+		}
+		a.logger.WarnContext(ctx, "login locked out",
+			"audit", true,
+			"subject", attempt.SubjectKey,
+			"failures", failures,
+			"locked_until", until,
+		)
+	}
+
+	if err := a.attemptRepo.Save(ctx, attempt); err != nil {
+		return e.NewStatusError(err, http.StatusInternalServerError)
+	}
+	return nil
+}
+
+func (a *authService) Unlock(ctx context.Context, req *v1.UnlockRequest) (err error) {
+	ctx, span := tracer.Start(ctx, "authService.Unlock")
+	defer func() { endSpan(span, err) }()
+
+	var keys []string
+	if req.Email != "" {
+		keys = append(keys, accountKey(req.Email))
+	}
+	if req.IP != "" {
+		keys = append(keys, ipKey(req.IP))
+	}
+	if len(keys) == 0 {
+		return ErrNothingToUnlock
+	}
+
+	return a.tx.Transaction(ctx, func(ctx context.Context) error {
+		for _, key := range keys {
+			if err := a.attemptRepo.Reset(ctx, key); err != nil {
+				return e.NewStatusError(err, http.StatusInternalServerError)
+			}
+			if err := a.audit.record(ctx, AuditLoginUnlock, "login", key, nil, nil); err != nil {
+				return e.NewStatusError(err, http.StatusInternalServerError)
+			}
+			a.logger.InfoContext(ctx, "login lockout cleared",
+				"audit", true,
+				"subject", key,
+			)
+		}
+		return nil
+	})
+}
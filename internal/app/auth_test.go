@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	mock_repository "github.com/giortzisg/go-boilerplate/test/mock"
+	"github.com/golang/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func Test_authService_Login(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy := DefaultLockoutPolicy()
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user := &model.User{Name: "Test User", Email: "test@example.com", Password: string(hash), Version: 1}
+	const (
+		account = "account:test@example.com"
+		ip      = "ip:203.0.113.7"
+	)
+
+	type mockExpect func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository)
+
+	fresh := func(key string) *model.LoginAttempt {
+		return &model.LoginAttempt{SubjectKey: key}
+	}
+	saved := func(failures int, locked bool) gomock.Matcher {
+		return attemptMatcher{failures: failures, locked: locked}
+	}
+
+	tests := []struct {
+		name           string
+		password       string
+		mock           mockExpect
+		wantStatus     int
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:     "Login successfully resets account failures",
+			password: "password123",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(&model.LoginAttempt{SubjectKey: account, Failures: 1, LastFailureAt: now.Add(-time.Minute)}, nil).Times(2)
+				attempts.EXPECT().Acquire(gomock.Any(), ip).Return(fresh(ip), nil).Times(2)
+				users.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user, nil)
+				attempts.EXPECT().Reset(gomock.Any(), account).Return(nil)
+				attempts.EXPECT().Reset(gomock.Any(), ip).Return(nil)
+			},
+		},
+		{
+			name:     "Login successfully keeps the failures of the ip",
+			password: "password123",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(fresh(account), nil).Times(2)
+				attempts.EXPECT().Acquire(gomock.Any(), ip).Return(&model.LoginAttempt{SubjectKey: ip, Failures: 2, LastFailureAt: now.Add(-time.Minute)}, nil).Times(2)
+				users.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user, nil)
+				attempts.EXPECT().Reset(gomock.Any(), account).Return(nil)
+				attempts.EXPECT().Save(gomock.Any(), saved(2, false)).Return(nil)
+			},
+		},
+		{
+			name:     "Wrong password records a failure for account and ip",
+			password: "wrong",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(fresh(account), nil).Times(2)
+				attempts.EXPECT().Acquire(gomock.Any(), ip).Return(fresh(ip), nil).Times(2)
+				users.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user, nil)
+				attempts.EXPECT().Save(gomock.Any(), saved(1, false)).Return(nil).Times(2)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:     "Unknown account is rejected like a wrong password",
+			password: "password123",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(fresh(account), nil).Times(2)
+				attempts.EXPECT().Acquire(gomock.Any(), ip).Return(fresh(ip), nil).Times(2)
+				users.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(nil, gorm.ErrRecordNotFound)
+				attempts.EXPECT().Save(gomock.Any(), saved(1, false)).Return(nil).Times(2)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:     "Reaching the threshold locks the account and resets its failures",
+			password: "wrong",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(&model.LoginAttempt{SubjectKey: account, Failures: policy.MaxAccountFailures - 1, LastFailureAt: now.Add(-time.Hour + time.Minute)}, nil).Times(2)
+				attempts.EXPECT().Acquire(gomock.Any(), ip).Return(fresh(ip), nil).Times(2)
+				users.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user, nil)
+				audits.EXPECT().Create(gomock.Any(), auditRecordOf(AuditLoginLockout)).Return(nil)
+				attempts.EXPECT().Save(gomock.Any(), saved(0, true)).Return(nil)
+				attempts.EXPECT().Save(gomock.Any(), saved(1, false)).Return(nil)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:     "Locked account is rejected before checking the password",
+			password: "password123",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				lockedUntil := now.Add(10 * time.Minute)
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(&model.LoginAttempt{SubjectKey: account, LastFailureAt: now, LockedUntil: &lockedUntil}, nil)
+			},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: 10 * time.Minute,
+		},
+		{
+			name:     "Expired lock is lifted with its failures",
+			password: "wrong",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				lockedUntil := now.Add(-time.Minute)
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(&model.LoginAttempt{SubjectKey: account, Failures: 4, LastFailureAt: now.Add(-time.Second), LockedUntil: &lockedUntil}, nil).Times(2)
+				attempts.EXPECT().Acquire(gomock.Any(), ip).Return(fresh(ip), nil).Times(2)
+				users.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user, nil)
+				attempts.EXPECT().Save(gomock.Any(), saved(1, false)).Return(nil).Times(2)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:     "Failure recorded while the password was checked throttles the login",
+			password: "password123",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(fresh(account), nil)
+				attempts.EXPECT().Acquire(gomock.Any(), ip).Return(fresh(ip), nil)
+				users.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(user, nil)
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(&model.LoginAttempt{SubjectKey: account, Failures: 1, LastFailureAt: now}, nil)
+			},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: time.Second,
+		},
+		{
+			name:     "Attempts are delayed progressively after failures",
+			password: "password123",
+			mock: func(users *mock_repository.MockUserRepository, attempts *mock_repository.MockLoginAttemptRepository, audits *mock_repository.MockAuditRepository) {
+				attempts.EXPECT().Acquire(gomock.Any(), account).Return(fresh(account), nil)
+				attempts.EXPECT().Acquire(gomock.Any(), ip).Return(&model.LoginAttempt{SubjectKey: ip, Failures: 3, LastFailureAt: now.Add(-time.Second)}, nil)
+			},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: 3 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			users := mock_repository.NewMockUserRepository(ctrl)
+			attempts := mock_repository.NewMockLoginAttemptRepository(ctrl)
+			audits := mock_repository.NewMockAuditRepository(ctrl)
+			attempts.EXPECT().Purge(gomock.Any(), now.Add(-policy.FailureWindow)).Return(nil)
+			tt.mock(users, attempts, audits)
+
+			a := NewAuthService(slog.New(slog.NewJSONHandler(os.Stdout, nil)), users, attempts, audits, passthroughTransaction(ctrl), policy).(*authService)
+			a.now = func() time.Time { return now }
+
+			got, err := a.Login(context.Background(), &v1.LoginRequest{Email: "test@example.com", Password: tt.password}, "203.0.113.7")
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("Login() error = %v", err)
+				}
+				if got.Email != user.Email {
+					t.Errorf("Login() = %v, want %v", got.Email, user.Email)
+				}
+				return
+			}
+
+			var statusErr interface{ HTTPStatus() int }
+			if !errors.As(err, &statusErr) || statusErr.HTTPStatus() != tt.wantStatus {
+				t.Fatalf("Login() error = %v, want status %d", err, tt.wantStatus)
+			}
+			if tt.wantRetryAfter != 0 {
+				var retryErr interface{ RetryAfter() time.Duration }
+				if !errors.As(err, &retryErr) || retryErr.RetryAfter() != tt.wantRetryAfter {
+					t.Errorf("Login() error = %v, want retry after %s", err, tt.wantRetryAfter)
+				}
+			}
+		})
+	}
+}
+
+type attemptMatcher struct {
+	failures int
+	locked   bool
+}
+
+func (m attemptMatcher) Matches(x interface{}) bool {
+	attempt, ok := x.(*model.LoginAttempt)
+	return ok && attempt.Failures == m.failures && (attempt.LockedUntil != nil) == m.locked
+}
+
+func (m attemptMatcher) String() string {
+	return fmt.Sprintf("has %d failures and locked %v", m.failures, m.locked)
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/internal/repository"
+	"github.com/google/uuid"
+)
+
+// outbox adds domain events to the outbox. Like audit records, callers add
+// them in the transaction of the change they describe, so an event is
+// published if and only if the change is committed.
+type outbox struct {
+	repo repository.OutboxRepository
+	now  func() time.Time
+}
+
+// add stores an event of eventType about aggregateID, with payload encoded
+// as JSON.
+func (o *outbox) add(ctx context.Context, eventType, aggregateID string, payload any) error {
+	data, err := stdjson.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := o.now()
+	return o.repo.Add(ctx, &model.OutboxEvent{
+		Id:          uuid.New(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		Payload:     string(data),
+		Status:      model.OutboxPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+	})
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	mock_repository "github.com/giortzisg/go-boilerplate/test/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_userService_Events(t *testing.T) {
+	id := uuid.New()
+	stored := func() *model.User {
+		return &model.User{Id: id, Name: "Ann", Email: "ann@example.com", Password: "hash", Version: 1}
+	}
+
+	tests := []struct {
+		name      string
+		expect    func(userRepo *mock_repository.MockUserRepository, auditRepo *mock_repository.MockAuditRepository)
+		change    func(u UserService) error
+		eventType string
+		payload   any
+		want      any
+	}{
+		{
+			name: "Create emits UserCreated",
+			expect: func(userRepo *mock_repository.MockUserRepository, auditRepo *mock_repository.MockAuditRepository) {
+				userRepo.EXPECT().GetByEmail(gomock.Any(), "ann@example.com").Return(nil, nil)
+				userRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserCreate)).Return(nil)
+			},
+			change: func(u UserService) error {
+				return u.Create(context.Background(), &v1.CreateUserRequest{Name: "Ann", Email: "ann@example.com", Password: "hunter2"})
+			},
+			eventType: v1.EventUserCreated,
+			payload:   &v1.UserCreatedEvent{},
+			want:      &v1.UserCreatedEvent{Name: "Ann", Email: "ann@example.com"},
+		},
+		{
+			name: "Update emits UserUpdated with the changed fields",
+			expect: func(userRepo *mock_repository.MockUserRepository, auditRepo *mock_repository.MockAuditRepository) {
+				userRepo.EXPECT().GetByEmail(gomock.Any(), "ann@example.com").Return(stored(), nil)
+				userRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user *model.User) error {
+					user.Version++
+					return nil
+				})
+				auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserUpdate)).Return(nil)
+			},
+			change: func(u UserService) error {
+				_, err := u.Update(context.Background(), &v1.UpdateUserRequest{Name: "Anna", Email: "ann@example.com"})
+				return err
+			},
+			eventType: v1.EventUserUpdated,
+			payload:   &v1.UserUpdatedEvent{},
+			want:      &v1.UserUpdatedEvent{Id: id, Name: "Anna", Email: "ann@example.com", Version: 2, Changed: []string{"name"}},
+		},
+		{
+			name: "Delete emits UserDeleted",
+			expect: func(userRepo *mock_repository.MockUserRepository, auditRepo *mock_repository.MockAuditRepository) {
+				userRepo.EXPECT().GetByID(gomock.Any(), id).Return(stored(), nil)
+				userRepo.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil)
+				auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserDelete)).Return(nil)
+			},
+			change: func(u UserService) error {
+				return u.Delete(context.Background(), &v1.DeleteUserRequest{Id: id})
+			},
+			eventType: v1.EventUserDeleted,
+			payload:   &v1.UserDeletedEvent{},
+			want:      &v1.UserDeletedEvent{Id: id},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			userRepo := mock_repository.NewMockUserRepository(ctrl)
+			u, auditRepo, outboxRepo := newUserServiceWithOutbox(ctrl, userRepo)
+			tt.expect(userRepo, auditRepo)
+
+			var event *model.OutboxEvent
+			outboxRepo.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, e *model.OutboxEvent) error {
+				event = e
+				return nil
+			})
+
+			assert.NoError(t, tt.change(u))
+			assert.Equal(t, tt.eventType, event.Type)
+			assert.Equal(t, model.OutboxPending, event.Status)
+			assert.False(t, event.AvailableAt.IsZero())
+			assert.NotContains(t, event.Payload, "hunter2")
+			assert.NotContains(t, event.Payload, "hash")
+
+			assert.NoError(t, json.Unmarshal([]byte(event.Payload), tt.payload))
+			// timestamps and generated ids are not compared
+			switch payload := tt.payload.(type) {
+			case *v1.UserCreatedEvent:
+				assert.Equal(t, payload.Id.String(), event.AggregateID)
+				assert.False(t, payload.CreatedAt.IsZero())
+				payload.Id, payload.CreatedAt = uuid.Nil, time.Time{}
+			case *v1.UserUpdatedEvent:
+				assert.Equal(t, id.String(), event.AggregateID)
+				assert.False(t, payload.UpdatedAt.IsZero())
+				payload.UpdatedAt = time.Time{}
+			case *v1.UserDeletedEvent:
+				assert.Equal(t, id.String(), event.AggregateID)
+				assert.False(t, payload.DeletedAt.IsZero())
+				payload.DeletedAt = time.Time{}
+			}
+			assert.Equal(t, tt.want, tt.payload)
+		})
+	}
+}
+
+func Test_userService_OutboxFailureFailsTheChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepo := mock_repository.NewMockUserRepository(ctrl)
+	u, auditRepo, outboxRepo := newUserServiceWithOutbox(ctrl, userRepo)
+
+	userRepo.EXPECT().GetByEmail(gomock.Any(), "ann@example.com").Return(&model.User{Id: uuid.New(), Name: "Ann", Email: "ann@example.com", Version: 1}, nil)
+	userRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserUpdate)).Return(nil)
+	errOutbox := errors.New("outbox table unavailable")
+	outboxRepo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(errOutbox)
+
+	_, err := u.Update(context.Background(), &v1.UpdateUserRequest{Name: "Anna", Email: "ann@example.com"})
+	assert.ErrorIs(t, err, errOutbox)
+}
@@ -7,33 +7,128 @@ import (
 	"github.com/giortzisg/go-boilerplate/api/v1"
 	"github.com/giortzisg/go-boilerplate/internal/model"
 	"github.com/giortzisg/go-boilerplate/internal/repository"
+	"github.com/giortzisg/go-boilerplate/pkg/audit"
 	e "github.com/giortzisg/go-boilerplate/pkg/error"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
 	"time"
 )
 
 var (
 	ErrUserNotFound = e.NewStatusError(errors.New("user not found"), http.StatusNotFound)
 	ErrUserExists   = e.NewStatusError(errors.New("user already exists"), http.StatusBadRequest)
+	ErrUserConflict = e.NewStatusError(errors.New("user was modified concurrently"), http.StatusConflict)
+	ErrUserModified = e.NewStatusError(errors.New("user does not match the expected version"), http.StatusPreconditionFailed)
+	ErrUserInvalid  = e.NewStatusError(errors.New("invalid user"), http.StatusUnprocessableEntity)
 )
 
 type UserService interface {
+	GetByID(ctx context.Context, req *v1.GetUserByIDRequest) (*v1.GetUserResponse, error)
 	GetByEmail(ctx context.Context, req *v1.GetUserByEmailRequest) (*v1.GetUserResponse, error)
 	Create(ctx context.Context, user *v1.CreateUserRequest) error
-	Update(ctx context.Context, user *v1.UpdateUserRequest) error
+	Update(ctx context.Context, user *v1.UpdateUserRequest) (*v1.GetUserResponse, error)
+	Patch(ctx context.Context, req *v1.PatchUserRequest) (*v1.GetUserResponse, error)
+	Delete(ctx context.Context, req *v1.DeleteUserRequest) error
 }
 
-func NewUserService(userRepository repository.UserRepository) UserService {
+// NewUserService creates a UserService. Every change to a user is written
+// together with its audit record and domain event in a transaction of tx.
+func NewUserService(userRepository repository.UserRepository, auditRepository repository.AuditRepository, outboxRepository repository.OutboxRepository, tx repository.Transaction) UserService {
 	return &userService{
-		userRepository,
+		userRepo: userRepository,
+		audit:    &auditLog{repo: auditRepository, now: time.Now},
+		outbox:   &outbox{repo: outboxRepository, now: time.Now},
+		tx:       tx,
 	}
 }
 
 type userService struct {
 	userRepo repository.UserRepository
+	audit    *auditLog
+	outbox   *outbox
+	tx       repository.Transaction
+}
+
+// userSnapshot is what the audit trail records of a user.
+func userSnapshot(user *model.User) map[string]any {
+	return map[string]any{
+		"name":     user.Name,
+		"email":    user.Email,
+		"password": user.Password,
+		"version":  user.Version,
+	}
+}
+
+func (u *userService) create(ctx context.Context, user *model.User) error {
+	return u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		if err := u.audit.record(ctx, AuditUserCreate, "user", user.Id.String(), nil, userSnapshot(user), "password"); err != nil {
+			return err
+		}
+		return u.outbox.add(ctx, v1.EventUserCreated, user.Id.String(), &v1.UserCreatedEvent{
+			Id:        user.Id,
+			Name:      user.Name,
+			Email:     user.Email,
+			CreatedAt: user.CreatedAt,
+		})
+	})
+}
+
+// update writes user, whose state before the change is before.
+func (u *userService) update(ctx context.Context, user *model.User, before map[string]any) error {
+	return u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		after := userSnapshot(user)
+		if err := u.audit.record(ctx, AuditUserUpdate, "user", user.Id.String(), before, after, "password"); err != nil {
+			return err
+		}
+		return u.outbox.add(ctx, v1.EventUserUpdated, user.Id.String(), &v1.UserUpdatedEvent{
+			Id:        user.Id,
+			Name:      user.Name,
+			Email:     user.Email,
+			Version:   user.Version,
+			Changed:   changedFields(before, after),
+			UpdatedAt: user.UpdatedAt,
+		})
+	})
+}
+
+// remove deletes user, whose state before the change is before.
+func (u *userService) remove(ctx context.Context, user *model.User, before map[string]any) error {
+	return u.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := u.userRepo.Delete(ctx, user); err != nil {
+			return err
+		}
+		if err := u.audit.record(ctx, AuditUserDelete, "user", user.Id.String(), before, nil, "password"); err != nil {
+			return err
+		}
+		return u.outbox.add(ctx, v1.EventUserDeleted, user.Id.String(), &v1.UserDeletedEvent{
+			Id:        user.Id,
+			DeletedAt: u.outbox.now(),
+		})
+	})
+}
+
+// changedFields lists the fields that differ between two user snapshots,
+// leaving out the version, which changes on every update.
+func changedFields(before, after map[string]any) []string {
+	changed := []string{}
+	for field := range audit.Diff(before, after) {
+		if field != "version" {
+			changed = append(changed, field)
+		}
+	}
+	sort.Strings(changed)
+	return changed
 }
 
 func (u *userService) getUserModelByEmail(ctx context.Context, email string) (*model.User, error) {
@@ -48,7 +143,29 @@ func (u *userService) getUserModelByEmail(ctx context.Context, email string) (*m
 	return user, nil
 }
 
-func (u *userService) GetByEmail(ctx context.Context, req *v1.GetUserByEmailRequest) (*v1.GetUserResponse, error) {
+func (u *userService) GetByID(ctx context.Context, req *v1.GetUserByIDRequest) (_ *v1.GetUserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "userService.GetByID")
+	defer func() { endSpan(span, err) }()
+
+	user, err := u.userRepo.GetByID(ctx, req.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	return &v1.GetUserResponse{
+		Name:    user.Name,
+		Email:   user.Email,
+		Version: user.Version,
+	}, nil
+}
+
+func (u *userService) GetByEmail(ctx context.Context, req *v1.GetUserByEmailRequest) (_ *v1.GetUserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "userService.GetByEmail")
+	defer func() { endSpan(span, err) }()
+
 	user, err := u.getUserModelByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, ErrUserNotFound) {
@@ -58,12 +175,16 @@ func (u *userService) GetByEmail(ctx context.Context, req *v1.GetUserByEmailRequ
 	}
 
 	return &v1.GetUserResponse{
-		Name:  user.Name,
-		Email: user.Email,
+		Name:    user.Name,
+		Email:   user.Email,
+		Version: user.Version,
 	}, nil
 }
 
-func (u *userService) Create(ctx context.Context, user *v1.CreateUserRequest) error {
+func (u *userService) Create(ctx context.Context, user *v1.CreateUserRequest) (err error) {
+	ctx, span := tracer.Start(ctx, "userService.Create")
+	defer func() { endSpan(span, err) }()
+
 	modelUser, err := u.getUserModelByEmail(ctx, user.Email)
 	if err != nil && !errors.Is(err, ErrUserNotFound) {
 		return e.NewStatusError(err, http.StatusInternalServerError)
@@ -78,11 +199,12 @@ func (u *userService) Create(ctx context.Context, user *v1.CreateUserRequest) er
 		return e.NewStatusError(fmt.Errorf("failed to hash password: %e", err), http.StatusInternalServerError)
 	}
 
-	if err = u.userRepo.Create(ctx, &model.User{
+	if err = u.create(ctx, &model.User{
 		Id:        uuid.New(),
 		Name:      user.Name,
 		Email:     user.Email,
 		Password:  string(hashedPassword),
+		Version:   1,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}); err != nil {
@@ -92,20 +214,136 @@ func (u *userService) Create(ctx context.Context, user *v1.CreateUserRequest) er
 	return nil
 }
 
-func (u *userService) Update(ctx context.Context, user *v1.UpdateUserRequest) error {
+func (u *userService) Update(ctx context.Context, user *v1.UpdateUserRequest) (_ *v1.GetUserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "userService.Update")
+	defer func() { endSpan(span, err) }()
+
 	modelUser, err := u.getUserModelByEmail(ctx, user.Email)
 	if err != nil {
 		if errors.Is(err, ErrUserNotFound) {
-			return ErrUserNotFound
+			return nil, ErrUserNotFound
 		}
-		return e.NewStatusError(err, http.StatusInternalServerError)
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
 	}
 
+	if user.Version != 0 && user.Version != modelUser.Version {
+		return nil, ErrUserModified
+	}
+
+	before := userSnapshot(modelUser)
 	modelUser.Name = user.Name
 	modelUser.UpdatedAt = time.Now()
-	if err = u.userRepo.Update(ctx, modelUser); err != nil {
+	if err = u.update(ctx, modelUser, before); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if user.Version != 0 {
+				return nil, ErrUserModified
+			}
+			return nil, ErrUserConflict
+		}
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	return &v1.GetUserResponse{
+		Name:    modelUser.Name,
+		Email:   modelUser.Email,
+		Version: modelUser.Version,
+	}, nil
+}
+
+func (u *userService) Patch(ctx context.Context, req *v1.PatchUserRequest) (_ *v1.GetUserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "userService.Patch")
+	defer func() { endSpan(span, err) }()
+
+	modelUser, err := u.userRepo.GetByID(ctx, req.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	if req.Version != 0 && req.Version != modelUser.Version {
+		return nil, ErrUserModified
+	}
+
+	name, email := modelUser.Name, modelUser.Email
+	if req.Name != nil {
+		name = *req.Name
+	}
+	if req.Email != nil {
+		email = *req.Email
+	}
+
+	if err = validateUser(name, email); err != nil {
+		return nil, err
+	}
+
+	if email != modelUser.Email {
+		existing, err := u.getUserModelByEmail(ctx, email)
+		if err != nil && !errors.Is(err, ErrUserNotFound) {
+			return nil, e.NewStatusError(err, http.StatusInternalServerError)
+		}
+		if existing != nil {
+			return nil, ErrUserExists
+		}
+	}
+
+	before := userSnapshot(modelUser)
+	modelUser.Name = name
+	modelUser.Email = email
+	modelUser.UpdatedAt = time.Now()
+	if err = u.update(ctx, modelUser, before); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if req.Version != 0 {
+				return nil, ErrUserModified
+			}
+			return nil, ErrUserConflict
+		}
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	return &v1.GetUserResponse{
+		Name:    modelUser.Name,
+		Email:   modelUser.Email,
+		Version: modelUser.Version,
+	}, nil
+}
+
+func (u *userService) Delete(ctx context.Context, req *v1.DeleteUserRequest) (err error) {
+	ctx, span := tracer.Start(ctx, "userService.Delete")
+	defer func() { endSpan(span, err) }()
+
+	modelUser, err := u.userRepo.GetByID(ctx, req.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
 		return e.NewStatusError(err, http.StatusInternalServerError)
 	}
 
+	if req.Version != 0 && req.Version != modelUser.Version {
+		return ErrUserModified
+	}
+
+	if err = u.remove(ctx, modelUser, userSnapshot(modelUser)); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if req.Version != 0 {
+				return ErrUserModified
+			}
+			return ErrUserConflict
+		}
+		return e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func validateUser(name, email string) error {
+	if strings.TrimSpace(name) == "" {
+		return e.NewStatusError(fmt.Errorf("%w: name must not be empty", ErrUserInvalid), http.StatusUnprocessableEntity)
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return e.NewStatusError(fmt.Errorf("%w: email %q is not a valid address", ErrUserInvalid, email), http.StatusUnprocessableEntity)
+	}
 	return nil
 }
@@ -10,9 +10,11 @@ import (
 
 	v1 "github.com/giortzisg/go-boilerplate/api/v1"
 	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/internal/repository"
 	mock_repository "github.com/giortzisg/go-boilerplate/test/mock"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 func Test_userService_Create(t *testing.T) {
@@ -84,10 +86,15 @@ func Test_userService_Create(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := mock_repository.NewMockUserRepository(gomock.NewController(t))
-			mockRepo.EXPECT().GetByEmail(context.Background(), tt.args.user.Email).Return(tt.mock.getByEmailReturn, nil)
+			ctrl := gomock.NewController(t)
+			mockRepo := mock_repository.NewMockUserRepository(ctrl)
+			u, auditRepo := newUserServiceWithMocks(ctrl, mockRepo)
+			mockRepo.EXPECT().GetByEmail(gomock.Any(), tt.args.user.Email).Return(tt.mock.getByEmailReturn, nil)
+			if tt.mock.getByEmailReturn == nil && tt.mock.createReturn == nil {
+				auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserCreate)).Return(nil)
+			}
 			if tt.mock.getByEmailReturn == nil {
-				mockRepo.EXPECT().Create(context.Background(), gomock.AssignableToTypeOf(&model.User{
+				mockRepo.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&model.User{
 					Id:        uuid.UUID{},
 					Name:      tt.args.user.Name,
 					Email:     tt.args.user.Email,
@@ -96,7 +103,6 @@ func Test_userService_Create(t *testing.T) {
 					UpdatedAt: time.Time{},
 				})).Return(tt.mock.createReturn)
 			}
-			u := NewUserService(mockRepo)
 			if err := u.Create(tt.args.ctx, tt.args.user); (err != nil) != tt.wantErr {
 				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -176,8 +182,8 @@ func Test_userService_GetByEmail(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := mock_repository.NewMockUserRepository(gomock.NewController(t))
-			mockRepo.EXPECT().GetByEmail(tt.args.ctx, tt.args.req.Email).Return(tt.mock.getByEmailReturn, tt.mock.getByEmailError)
-			u := NewUserService(mockRepo)
+			mockRepo.EXPECT().GetByEmail(gomock.Any(), tt.args.req.Email).Return(tt.mock.getByEmailReturn, tt.mock.getByEmailError)
+			u := NewUserService(mockRepo, nil, nil, nil)
 			got, err := u.GetByEmail(tt.args.ctx, tt.args.req)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetByEmail() error = %v, wantErr %v", err, tt.wantErr)
@@ -199,6 +205,7 @@ func Test_userService_Update(t *testing.T) {
 		getByEmailReturn interface{}
 		getByEmailError  error
 		updateReturn     error
+		skipUpdate       bool
 	}
 
 	tests := []struct {
@@ -259,18 +266,228 @@ func Test_userService_Update(t *testing.T) {
 				updateReturn:    errors.New("database error"),
 			},
 		},
+		{
+			name: "Update user with stale If-Match version",
+			args: args{
+				ctx: context.Background(),
+				user: &v1.UpdateUserRequest{
+					Email:   "test@example.com",
+					Name:    "Updated User",
+					Version: 1,
+				},
+			},
+			wantErr: true,
+			mock: mockExpect{
+				getByEmailReturn: &model.User{
+					Email:   "test@example.com",
+					Version: 2,
+				},
+				skipUpdate: true,
+			},
+		},
+		{
+			name: "Update user modified concurrently",
+			args: args{
+				ctx: context.Background(),
+				user: &v1.UpdateUserRequest{
+					Email: "test@example.com",
+					Name:  "Updated User",
+				},
+			},
+			wantErr: true,
+			mock: mockExpect{
+				getByEmailReturn: &model.User{
+					Email:   "test@example.com",
+					Version: 2,
+				},
+				updateReturn: repository.ErrVersionConflict,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := mock_repository.NewMockUserRepository(gomock.NewController(t))
-			mockRepo.EXPECT().GetByEmail(tt.args.ctx, tt.args.user.Email).Return(tt.mock.getByEmailReturn, tt.mock.getByEmailError)
-			if tt.mock.getByEmailReturn != nil {
-				mockRepo.EXPECT().Update(tt.args.ctx, gomock.AssignableToTypeOf(&model.User{})).Return(tt.mock.updateReturn)
+			ctrl := gomock.NewController(t)
+			mockRepo := mock_repository.NewMockUserRepository(ctrl)
+			u, auditRepo := newUserServiceWithMocks(ctrl, mockRepo)
+			mockRepo.EXPECT().GetByEmail(gomock.Any(), tt.args.user.Email).Return(tt.mock.getByEmailReturn, tt.mock.getByEmailError)
+			if tt.mock.getByEmailReturn != nil && !tt.mock.skipUpdate {
+				mockRepo.EXPECT().Update(gomock.Any(), gomock.AssignableToTypeOf(&model.User{})).DoAndReturn(func(_ context.Context, user *model.User) error {
+					if tt.mock.updateReturn == nil {
+						user.Version++
+					}
+					return tt.mock.updateReturn
+				})
+				if tt.mock.updateReturn == nil {
+					auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserUpdate)).Return(nil)
+				}
+			}
+			var version uint
+			if stored, ok := tt.mock.getByEmailReturn.(*model.User); ok {
+				version = stored.Version
 			}
-			u := NewUserService(mockRepo)
-			if err := u.Update(tt.args.ctx, tt.args.user); (err != nil) != tt.wantErr {
+			got, err := u.Update(tt.args.ctx, tt.args.user)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if err == nil && got.Version != version+1 {
+				t.Errorf("Update() version = %d, want %d", got.Version, version+1)
+			}
+		})
+	}
+}
+
+func Test_userService_Patch(t *testing.T) {
+	id := uuid.New()
+	stored := func() *model.User {
+		return &model.User{
+			Id:      id,
+			Name:    "Test User",
+			Email:   "test@example.com",
+			Version: 2,
+		}
+	}
+
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name        string
+		req         *v1.PatchUserRequest
+		want        *v1.GetUserResponse
+		wantErr     error
+		lookupEmail bool
+		emailTaken  bool
+		update      bool
+	}{
+		{
+			name:   "Changes only the name",
+			req:    &v1.PatchUserRequest{Id: id, Name: ptr("Patched User"), Version: 2},
+			want:   &v1.GetUserResponse{Name: "Patched User", Email: "test@example.com", Version: 2},
+			update: true,
+		},
+		{
+			name:        "Changes the email",
+			req:         &v1.PatchUserRequest{Id: id, Email: ptr("new@example.com")},
+			want:        &v1.GetUserResponse{Name: "Test User", Email: "new@example.com", Version: 2},
+			lookupEmail: true,
+			update:      true,
+		},
+		{
+			name:    "Empty name is rejected",
+			req:     &v1.PatchUserRequest{Id: id, Name: ptr("")},
+			wantErr: ErrUserInvalid,
+		},
+		{
+			name:        "Email taken by another user",
+			req:         &v1.PatchUserRequest{Id: id, Email: ptr("taken@example.com")},
+			want:        &v1.GetUserResponse{Email: "taken@example.com"},
+			wantErr:     ErrUserExists,
+			lookupEmail: true,
+			emailTaken:  true,
+		},
+		{
+			name:    "Stale version",
+			req:     &v1.PatchUserRequest{Id: id, Name: ptr("Patched User"), Version: 1},
+			wantErr: ErrUserModified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctrl := gomock.NewController(t)
+			mockRepo := mock_repository.NewMockUserRepository(ctrl)
+			u, auditRepo := newUserServiceWithMocks(ctrl, mockRepo)
+			mockRepo.EXPECT().GetByID(gomock.Any(), id).Return(stored(), nil)
+			if tt.lookupEmail {
+				if tt.emailTaken {
+					mockRepo.EXPECT().GetByEmail(gomock.Any(), tt.want.Email).Return(&model.User{Id: uuid.New(), Email: tt.want.Email}, nil)
+				} else {
+					mockRepo.EXPECT().GetByEmail(gomock.Any(), tt.want.Email).Return(nil, gorm.ErrRecordNotFound)
+				}
+			}
+			if tt.update {
+				mockRepo.EXPECT().Update(gomock.Any(), gomock.AssignableToTypeOf(&model.User{})).Return(nil)
+				auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserUpdate)).Return(nil)
+			}
+			got, err := u.Patch(ctx, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Patch() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Patch() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Patch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_userService_Delete(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name      string
+		req       *v1.DeleteUserRequest
+		lookupErr error
+		deleteErr error
+		wantErr   error
+	}{
+		{
+			name: "Deletes the user",
+			req:  &v1.DeleteUserRequest{Id: id},
+		},
+		{
+			name: "Matching If-Match version",
+			req:  &v1.DeleteUserRequest{Id: id, Version: 2},
+		},
+		{
+			name:    "Stale If-Match version",
+			req:     &v1.DeleteUserRequest{Id: id, Version: 1},
+			wantErr: ErrUserModified,
+		},
+		{
+			name:      "User not found",
+			req:       &v1.DeleteUserRequest{Id: id},
+			lookupErr: gorm.ErrRecordNotFound,
+			wantErr:   ErrUserNotFound,
+		},
+		{
+			name:      "Concurrent update",
+			req:       &v1.DeleteUserRequest{Id: id},
+			deleteErr: repository.ErrVersionConflict,
+			wantErr:   ErrUserConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := mock_repository.NewMockUserRepository(ctrl)
+			u, auditRepo := newUserServiceWithMocks(ctrl, mockRepo)
+			if tt.lookupErr != nil {
+				mockRepo.EXPECT().GetByID(gomock.Any(), id).Return(nil, tt.lookupErr)
+			} else {
+				mockRepo.EXPECT().GetByID(gomock.Any(), id).Return(&model.User{Id: id, Name: "Test User", Email: "test@example.com", Version: 2}, nil)
+			}
+			if tt.lookupErr == nil && !errors.Is(tt.wantErr, ErrUserModified) {
+				mockRepo.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&model.User{})).Return(tt.deleteErr)
+				if tt.deleteErr == nil {
+					auditRepo.EXPECT().Create(gomock.Any(), auditRecordOf(AuditUserDelete)).Return(nil)
+				}
+			}
+
+			err := u.Delete(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Delete() unexpected error = %v", err)
+			}
 		})
 	}
 }
@@ -0,0 +1,367 @@
+package app
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/internal/repository"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+	"github.com/giortzisg/go-boilerplate/pkg/events"
+	"github.com/giortzisg/go-boilerplate/pkg/webhook"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Paging of the delivery history.
+const (
+	DefaultWebhookPageSize = 50
+	MaxWebhookPageSize     = 500
+)
+
+// Limits of a webhook, matching the columns they are stored in.
+const (
+	maxWebhookURLLength    = 2048
+	maxWebhookSecretLength = 255
+)
+
+// WebhookEventTypes are the events webhooks can subscribe to, besides
+// events.AllEvents.
+var WebhookEventTypes = []string{v1.EventUserCreated, v1.EventUserUpdated, v1.EventUserDeleted}
+
+var (
+	ErrWebhookNotFound         = e.NewStatusError(errors.New("webhook not found"), http.StatusNotFound)
+	ErrWebhookInvalid          = e.NewStatusError(errors.New("invalid webhook"), http.StatusUnprocessableEntity)
+	ErrWebhookDeliveryNotFound = e.NewStatusError(errors.New("webhook delivery not found"), http.StatusNotFound)
+	ErrInvalidWebhookQuery     = e.NewStatusError(errors.New("invalid webhook delivery query"), http.StatusBadRequest)
+)
+
+type WebhookService interface {
+	Create(ctx context.Context, req *v1.CreateWebhookRequest) (*v1.Webhook, error)
+	List(ctx context.Context) (*v1.ListWebhooksResponse, error)
+	Get(ctx context.Context, id uuid.UUID) (*v1.Webhook, error)
+	Update(ctx context.Context, req *v1.UpdateWebhookRequest) (*v1.Webhook, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, req *v1.ListWebhookDeliveriesRequest) (*v1.ListWebhookDeliveriesResponse, error)
+	GetDelivery(ctx context.Context, webhookID, id uuid.UUID) (*v1.WebhookDeliveryDetail, error)
+	// Redeliver sends a delivery again, whether it succeeded or failed.
+	Redeliver(ctx context.Context, webhookID, id uuid.UUID) error
+}
+
+func NewWebhookService(webhookRepository repository.WebhookRepository) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepository,
+		now:         time.Now,
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+	}
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	now         func() time.Time
+	lookup      func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+func (s *webhookService) Create(ctx context.Context, req *v1.CreateWebhookRequest) (_ *v1.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "webhookService.Create")
+	defer func() { endSpan(span, err) }()
+
+	if err = s.validateWebhook(ctx, req.URL, req.EventTypes); err != nil {
+		return nil, err
+	}
+	if len(req.Secret) > maxWebhookSecretLength {
+		return nil, fmt.Errorf("%w: secret must be at most %d characters", ErrWebhookInvalid, maxWebhookSecretLength)
+	}
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = webhook.NewSecret(); err != nil {
+			return nil, e.NewStatusError(err, http.StatusInternalServerError)
+		}
+	}
+
+	now := s.now()
+	hook := &model.Webhook{
+		Id:         uuid.New(),
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: strings.Join(req.EventTypes, ","),
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err = s.webhookRepo.Create(ctx, hook); err != nil {
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	response := toWebhookResponse(hook)
+	response.Secret = hook.Secret
+	return response, nil
+}
+
+func (s *webhookService) List(ctx context.Context) (_ *v1.ListWebhooksResponse, err error) {
+	ctx, span := tracer.Start(ctx, "webhookService.List")
+	defer func() { endSpan(span, err) }()
+
+	hooks, err := s.webhookRepo.List(ctx)
+	if err != nil {
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	response := &v1.ListWebhooksResponse{Webhooks: make([]v1.Webhook, 0, len(hooks))}
+	for i := range hooks {
+		response.Webhooks = append(response.Webhooks, *toWebhookResponse(&hooks[i]))
+	}
+	return response, nil
+}
+
+func (s *webhookService) Get(ctx context.Context, id uuid.UUID) (_ *v1.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "webhookService.Get")
+	defer func() { endSpan(span, err) }()
+
+	hook, err := s.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookResponse(hook), nil
+}
+
+func (s *webhookService) Update(ctx context.Context, req *v1.UpdateWebhookRequest) (_ *v1.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "webhookService.Update")
+	defer func() { endSpan(span, err) }()
+
+	if err = s.validateWebhook(ctx, req.URL, req.EventTypes); err != nil {
+		return nil, err
+	}
+	hook, err := s.getWebhook(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	hook.URL = req.URL
+	hook.EventTypes = strings.Join(req.EventTypes, ",")
+	hook.Active = req.Active
+	hook.UpdatedAt = s.now()
+	if err = s.webhookRepo.Update(ctx, hook); err != nil {
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+	return toWebhookResponse(hook), nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "webhookService.Delete")
+	defer func() { endSpan(span, err) }()
+
+	if err = s.webhookRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWebhookNotFound
+		}
+		return e.NewStatusError(err, http.StatusInternalServerError)
+	}
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, req *v1.ListWebhookDeliveriesRequest) (_ *v1.ListWebhookDeliveriesResponse, err error) {
+	ctx, span := tracer.Start(ctx, "webhookService.ListDeliveries")
+	defer func() { endSpan(span, err) }()
+
+	limit := req.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultWebhookPageSize
+	case limit < 0, limit > MaxWebhookPageSize:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidWebhookQuery, MaxWebhookPageSize)
+	}
+	if req.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidWebhookQuery)
+	}
+	switch req.Status {
+	case "", model.WebhookDeliveryPending, model.WebhookDeliverySucceeded, model.WebhookDeliveryFailed:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidWebhookQuery, req.Status)
+	}
+	if _, err = s.getWebhook(ctx, req.WebhookId); err != nil {
+		return nil, err
+	}
+
+	deliveries, total, err := s.webhookRepo.ListDeliveries(ctx, repository.WebhookDeliveryFilter{
+		WebhookID: req.WebhookId,
+		Status:    req.Status,
+		Limit:     limit,
+		Offset:    req.Offset,
+	})
+	if err != nil {
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	response := &v1.ListWebhookDeliveriesResponse{
+		Deliveries: make([]v1.WebhookDelivery, 0, len(deliveries)),
+		Total:      total,
+		Limit:      limit,
+		Offset:     req.Offset,
+	}
+	for i := range deliveries {
+		response.Deliveries = append(response.Deliveries, toWebhookDeliveryResponse(&deliveries[i]))
+	}
+	return response, nil
+}
+
+func (s *webhookService) GetDelivery(ctx context.Context, webhookID, id uuid.UUID) (_ *v1.WebhookDeliveryDetail, err error) {
+	ctx, span := tracer.Start(ctx, "webhookService.GetDelivery")
+	defer func() { endSpan(span, err) }()
+
+	delivery, err := s.getDelivery(ctx, webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := s.webhookRepo.ListAttempts(ctx, delivery.Id)
+	if err != nil {
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+
+	response := &v1.WebhookDeliveryDetail{
+		WebhookDelivery: toWebhookDeliveryResponse(delivery),
+		Payload:         stdjson.RawMessage(delivery.Payload),
+		History:         make([]v1.WebhookAttempt, 0, len(attempts)),
+	}
+	for _, attempt := range attempts {
+		response.History = append(response.History, v1.WebhookAttempt{
+			Attempt:      attempt.Attempt,
+			StatusCode:   attempt.StatusCode,
+			Error:        attempt.Error,
+			ResponseBody: attempt.ResponseBody,
+			DurationMs:   attempt.DurationMs,
+			CreatedAt:    attempt.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, webhookID, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "webhookService.Redeliver")
+	defer func() { endSpan(span, err) }()
+
+	if _, err = s.getDelivery(ctx, webhookID, id); err != nil {
+		return err
+	}
+	if err = s.webhookRepo.Redeliver(ctx, id); err != nil {
+		return e.NewStatusError(err, http.StatusInternalServerError)
+	}
+	return nil
+}
+
+func (s *webhookService) getWebhook(ctx context.Context, id uuid.UUID) (*model.Webhook, error) {
+	hook, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+	return hook, nil
+}
+
+func (s *webhookService) getDelivery(ctx context.Context, webhookID, id uuid.UUID) (*model.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.GetDelivery(ctx, webhookID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, e.NewStatusError(err, http.StatusInternalServerError)
+	}
+	return delivery, nil
+}
+
+// validateWebhook checks that rawURL is an absolute http or https URL of a
+// public host and that eventTypes lists known events only.
+func (s *webhookService) validateWebhook(ctx context.Context, rawURL string, eventTypes []string) error {
+	if len(rawURL) > maxWebhookURLLength {
+		return fmt.Errorf("%w: url must be at most %d characters", ErrWebhookInvalid, maxWebhookURLLength)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrWebhookInvalid)
+	}
+	if err = s.checkHost(ctx, u.Hostname()); err != nil {
+		return err
+	}
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("%w: event_types must not be empty", ErrWebhookInvalid)
+	}
+	for _, eventType := range eventTypes {
+		if eventType != events.AllEvents && !slices.Contains(WebhookEventTypes, eventType) {
+			return fmt.Errorf("%w: unknown event type %q", ErrWebhookInvalid, eventType)
+		}
+	}
+	return nil
+}
+
+// checkHost rejects hosts that are or resolve to addresses which are not
+// public. The Sender checks again when connecting, as what a name resolves
+// to can change after registration.
+func (s *webhookService) checkHost(ctx context.Context, host string) error {
+	addr, err := netip.ParseAddr(host)
+	addrs := []netip.Addr{addr}
+	if err != nil {
+		if addrs, err = s.lookup(ctx, host); err != nil || len(addrs) == 0 {
+			return fmt.Errorf("%w: url host %q cannot be resolved", ErrWebhookInvalid, host)
+		}
+	}
+	for _, addr := range addrs {
+		if !webhook.IsPublic(addr) {
+			return fmt.Errorf("%w: url host %q is not a public address", ErrWebhookInvalid, host)
+		}
+	}
+	return nil
+}
+
+// subscribed reports whether hook receives events of eventType.
+func subscribed(hook *model.Webhook, eventType string) bool {
+	for _, t := range strings.Split(hook.EventTypes, ",") {
+		if t == events.AllEvents || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func toWebhookResponse(hook *model.Webhook) *v1.Webhook {
+	return &v1.Webhook{
+		Id:         hook.Id,
+		URL:        hook.URL,
+		EventTypes: strings.Split(hook.EventTypes, ","),
+		Active:     hook.Active,
+		CreatedAt:  hook.CreatedAt,
+		UpdatedAt:  hook.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryResponse(delivery *model.WebhookDelivery) v1.WebhookDelivery {
+	response := v1.WebhookDelivery{
+		Id:             delivery.Id,
+		WebhookId:      delivery.WebhookID,
+		EventId:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+	if delivery.Status == model.WebhookDeliveryPending {
+		response.NextAttemptAt = &delivery.AvailableAt
+	}
+	return response
+}
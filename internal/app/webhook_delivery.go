@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/internal/repository"
+	"github.com/giortzisg/go-boilerplate/pkg/events"
+	"github.com/giortzisg/go-boilerplate/pkg/webhook"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrWebhookDisabled = errors.New("webhook is disabled")
+
+// NewWebhookFanOut returns the events.Handler that queues a delivery of
+// every event for each active webhook subscribed to it. Subscribe it to
+// events.AllEvents of the outbox dispatcher.
+func NewWebhookFanOut(webhookRepository repository.WebhookRepository) events.Handler {
+	f := &webhookFanOut{webhookRepo: webhookRepository, now: time.Now}
+	return f.handle
+}
+
+type webhookFanOut struct {
+	webhookRepo repository.WebhookRepository
+	now         func() time.Time
+}
+
+func (f *webhookFanOut) handle(ctx context.Context, event events.Event) error {
+	hooks, err := f.webhookRepo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the body is fixed here so that every attempt posts the same bytes
+	body, err := stdjson.Marshal(&v1.WebhookPayload{
+		Id:         event.ID,
+		Type:       event.Type,
+		OccurredAt: event.OccurredAt,
+		Data:       stdjson.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := f.now()
+	var deliveries []model.WebhookDelivery
+	for i := range hooks {
+		if !subscribed(&hooks[i], event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, model.WebhookDelivery{
+			Id:          uuid.New(),
+			WebhookID:   hooks[i].Id,
+			EventID:     event.ID,
+			EventType:   event.Type,
+			Payload:     string(body),
+			Status:      model.WebhookDeliveryPending,
+			AvailableAt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+	return f.webhookRepo.AddDeliveries(ctx, deliveries)
+}
+
+// NewWebhookSender returns the events.Handler that posts the deliveries
+// claimed from repository.NewWebhookDeliveryStore and records every
+// attempt. Deliveries of a disabled webhook wait, and fail like any other
+// once they run out of attempts.
+func NewWebhookSender(logger *slog.Logger, webhookRepository repository.WebhookRepository, sender *webhook.Sender) events.Handler {
+	s := &webhookSender{logger: logger, webhookRepo: webhookRepository, sender: sender, now: time.Now}
+	return s.handle
+}
+
+type webhookSender struct {
+	logger      *slog.Logger
+	webhookRepo repository.WebhookRepository
+	sender      *webhook.Sender
+	now         func() time.Time
+}
+
+func (s *webhookSender) handle(ctx context.Context, delivery events.Event) error {
+	deliveryID, err := uuid.Parse(delivery.ID)
+	if err != nil {
+		return err
+	}
+	webhookID, err := uuid.Parse(delivery.AggregateID)
+	if err != nil {
+		return err
+	}
+	hook, err := s.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// the webhook was deleted along with its deliveries
+			return nil
+		}
+		return err
+	}
+	if !hook.Active {
+		return ErrWebhookDisabled
+	}
+
+	start := s.now()
+	resp, sendErr := s.sender.Send(ctx, webhook.Request{
+		ID:     delivery.ID,
+		URL:    hook.URL,
+		Secret: hook.Secret,
+		Body:   delivery.Payload,
+	})
+	attempt := &model.WebhookAttempt{
+		Id:         uuid.New(),
+		DeliveryID: deliveryID,
+		Attempt:    delivery.Attempts,
+		DurationMs: s.now().Sub(start).Milliseconds(),
+		CreatedAt:  start,
+	}
+	if resp != nil {
+		attempt.StatusCode = resp.StatusCode
+		attempt.ResponseBody = strings.ToValidUTF8(string(resp.Body), "\uFFFD")
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+	}
+	// a lost attempt record is not worth sending the webhook again
+	if err := s.webhookRepo.AddAttempt(ctx, attempt); err != nil {
+		s.logger.ErrorContext(ctx, "Error recording webhook attempt", "delivery_id", delivery.ID, "error", err)
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("posting to webhook %s: %w", hook.Id, sendErr)
+	}
+	return nil
+}
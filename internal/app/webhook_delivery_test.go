@@ -0,0 +1,158 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/pkg/events"
+	"github.com/giortzisg/go-boilerplate/pkg/webhook"
+	mock_repository "github.com/giortzisg/go-boilerplate/test/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_webhookFanOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepo := mock_repository.NewMockWebhookRepository(ctrl)
+	fanOut := NewWebhookFanOut(webhookRepo)
+
+	all, created, deleted := uuid.New(), uuid.New(), uuid.New()
+	webhookRepo.EXPECT().ListActive(gomock.Any()).Return([]model.Webhook{
+		{Id: all, EventTypes: "*"},
+		{Id: created, EventTypes: "user.updated,user.created"},
+		{Id: deleted, EventTypes: "user.deleted"},
+	}, nil)
+
+	occurredAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var deliveries []model.WebhookDelivery
+	webhookRepo.EXPECT().AddDeliveries(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, d []model.WebhookDelivery) error {
+		deliveries = d
+		return nil
+	})
+
+	err := fanOut(context.Background(), events.Event{
+		ID:          "evt-1",
+		Type:        v1.EventUserCreated,
+		AggregateID: "user-1",
+		Payload:     []byte(`{"name":"Ann"}`),
+		OccurredAt:  occurredAt,
+	})
+	assert.NoError(t, err)
+	if !assert.Len(t, deliveries, 2) {
+		return
+	}
+	assert.Equal(t, all, deliveries[0].WebhookID)
+	assert.Equal(t, created, deliveries[1].WebhookID)
+	for _, delivery := range deliveries {
+		assert.Equal(t, "evt-1", delivery.EventID)
+		assert.Equal(t, model.WebhookDeliveryPending, delivery.Status)
+		assert.JSONEq(t, `{"id":"evt-1","type":"user.created","occurred_at":"2024-01-01T12:00:00Z","data":{"name":"Ann"}}`, delivery.Payload)
+	}
+}
+
+func Test_webhookSender(t *testing.T) {
+	const secret = "whsec_test"
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify(secret, r.Header, body, 0, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var payload v1.WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, r.Header.Get(webhook.IDHeader)+" "+payload.Type)
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "thanks")
+	}))
+	defer receiver.Close()
+
+	webhookID, deliveryID := uuid.New(), uuid.New()
+	delivery := events.Event{
+		ID:          deliveryID.String(),
+		Type:        v1.EventUserCreated,
+		AggregateID: webhookID.String(),
+		Payload:     []byte(`{"id":"evt-1","type":"user.created","data":{}}`),
+		Attempts:    2,
+	}
+
+	tests := []struct {
+		name       string
+		webhook    *model.Webhook
+		wantErr    bool
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Delivered",
+			webhook:    &model.Webhook{Id: webhookID, URL: receiver.URL + "/up", Secret: secret, Active: true},
+			wantStatus: http.StatusOK,
+			wantBody:   "thanks",
+		},
+		{
+			name:       "Endpoint failing",
+			webhook:    &model.Webhook{Id: webhookID, URL: receiver.URL + "/down", Secret: secret, Active: true},
+			wantErr:    true,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Signed with a secret the receiver does not know",
+			webhook:    &model.Webhook{Id: webhookID, URL: receiver.URL + "/up", Secret: "whsec_other", Active: true},
+			wantErr:    true,
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   webhook.ErrInvalidSignature.Error() + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			webhookRepo := mock_repository.NewMockWebhookRepository(ctrl)
+			send := NewWebhookSender(slog.New(slog.NewTextHandler(io.Discard, nil)), webhookRepo, webhook.NewSender(webhook.WithHTTPClient(receiver.Client())))
+
+			webhookRepo.EXPECT().GetByID(gomock.Any(), webhookID).Return(tt.webhook, nil)
+			var attempt *model.WebhookAttempt
+			webhookRepo.EXPECT().AddAttempt(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, a *model.WebhookAttempt) error {
+				attempt = a
+				return nil
+			})
+
+			err := send(context.Background(), delivery)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, deliveryID, attempt.DeliveryID)
+			assert.Equal(t, 2, attempt.Attempt)
+			assert.Equal(t, tt.wantStatus, attempt.StatusCode)
+			assert.Equal(t, tt.wantBody, attempt.ResponseBody)
+			assert.Equal(t, tt.wantErr, attempt.Error != "")
+		})
+	}
+	assert.Equal(t, []string{deliveryID.String() + " user.created", deliveryID.String() + " user.created"}, received)
+}
+
+func Test_webhookSender_DisabledWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepo := mock_repository.NewMockWebhookRepository(ctrl)
+	send := NewWebhookSender(slog.New(slog.NewTextHandler(io.Discard, nil)), webhookRepo, webhook.NewSender())
+	webhookID := uuid.New()
+
+	webhookRepo.EXPECT().GetByID(gomock.Any(), webhookID).Return(&model.Webhook{Id: webhookID, URL: "http://127.0.0.1:1", Active: false}, nil)
+
+	err := send(context.Background(), events.Event{ID: uuid.NewString(), AggregateID: webhookID.String()})
+	assert.ErrorIs(t, err, ErrWebhookDisabled)
+}
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/internal/repository"
+	mock_repository "github.com/giortzisg/go-boilerplate/test/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestWebhookService resolves example.com hosts without DNS.
+func newTestWebhookService(webhookRepo repository.WebhookRepository) WebhookService {
+	s := NewWebhookService(webhookRepo).(*webhookService)
+	s.lookup = func(_ context.Context, host string) ([]netip.Addr, error) {
+		switch host {
+		case "example.com", "hooks.example.com":
+			return []netip.Addr{netip.MustParseAddr("93.184.215.14")}, nil
+		case "internal.example.com":
+			return []netip.Addr{netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("10.0.0.5")}, nil
+		case "localhost":
+			return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	return s
+}
+
+func Test_webhookService_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *v1.CreateWebhookRequest
+		wantErr error
+	}{
+		{
+			name: "Generates a secret",
+			req:  &v1.CreateWebhookRequest{URL: "https://example.com/hooks", EventTypes: []string{v1.EventUserCreated, v1.EventUserDeleted}},
+		},
+		{
+			name: "Keeps the given secret",
+			req:  &v1.CreateWebhookRequest{URL: "http://hooks.example.com:8000/hooks", Secret: "whsec_mine", EventTypes: []string{"*"}},
+		},
+		{
+			name: "Public address",
+			req:  &v1.CreateWebhookRequest{URL: "https://93.184.215.14/hooks", EventTypes: []string{"*"}},
+		},
+		{
+			name:    "Loopback address",
+			req:     &v1.CreateWebhookRequest{URL: "http://127.0.0.1:8000/hooks", EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "Metadata service",
+			req:     &v1.CreateWebhookRequest{URL: "http://169.254.169.254/latest/meta-data", EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "Host resolving to loopback",
+			req:     &v1.CreateWebhookRequest{URL: "http://localhost:8000/hooks", EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "Host resolving to a private address among public ones",
+			req:     &v1.CreateWebhookRequest{URL: "https://internal.example.com/hooks", EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "Host that does not resolve",
+			req:     &v1.CreateWebhookRequest{URL: "https://missing.example.com/hooks", EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "Secret too long",
+			req:     &v1.CreateWebhookRequest{URL: "https://example.com/hooks", Secret: strings.Repeat("s", 256), EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "URL too long",
+			req:     &v1.CreateWebhookRequest{URL: "https://example.com/" + strings.Repeat("a", 2048), EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "Relative URL",
+			req:     &v1.CreateWebhookRequest{URL: "/hooks", EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "Unsupported scheme",
+			req:     &v1.CreateWebhookRequest{URL: "ftp://example.com/hooks", EventTypes: []string{"*"}},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "No event types",
+			req:     &v1.CreateWebhookRequest{URL: "https://example.com/hooks"},
+			wantErr: ErrWebhookInvalid,
+		},
+		{
+			name:    "Unknown event type",
+			req:     &v1.CreateWebhookRequest{URL: "https://example.com/hooks", EventTypes: []string{"user.renamed"}},
+			wantErr: ErrWebhookInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			webhookRepo := mock_repository.NewMockWebhookRepository(ctrl)
+			s := newTestWebhookService(webhookRepo)
+
+			var stored *model.Webhook
+			if tt.wantErr == nil {
+				webhookRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w *model.Webhook) error {
+					stored = w
+					return nil
+				})
+			}
+
+			got, err := s.Create(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, got.Active)
+			assert.Equal(t, tt.req.EventTypes, got.EventTypes)
+			assert.Equal(t, stored.Secret, got.Secret)
+			if tt.req.Secret != "" {
+				assert.Equal(t, tt.req.Secret, got.Secret)
+			} else {
+				assert.Regexp(t, "^whsec_[0-9a-f]{64}$", got.Secret)
+			}
+		})
+	}
+}
+
+func Test_webhookService_SecretIsOnlyReturnedOnCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepo := mock_repository.NewMockWebhookRepository(ctrl)
+	s := newTestWebhookService(webhookRepo)
+	id := uuid.New()
+
+	webhookRepo.EXPECT().GetByID(gomock.Any(), id).Return(&model.Webhook{Id: id, URL: "https://example.com/hooks", Secret: "whsec_mine", EventTypes: "*", Active: true}, nil)
+	webhookRepo.EXPECT().List(gomock.Any()).Return([]model.Webhook{{Id: id, Secret: "whsec_mine", EventTypes: "*"}}, nil)
+
+	got, err := s.Get(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Empty(t, got.Secret)
+	list, err := s.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, list.Webhooks[0].Secret)
+}
+
+func Test_webhookService_Update(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepo := mock_repository.NewMockWebhookRepository(ctrl)
+	s := newTestWebhookService(webhookRepo)
+	id := uuid.New()
+
+	webhookRepo.EXPECT().GetByID(gomock.Any(), id).Return(&model.Webhook{Id: id, URL: "https://example.com/old", Secret: "whsec_mine", EventTypes: "*", Active: true}, nil)
+	webhookRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w *model.Webhook) error {
+		assert.Equal(t, "whsec_mine", w.Secret)
+		assert.Equal(t, "user.created,user.updated", w.EventTypes)
+		return nil
+	})
+
+	got, err := s.Update(context.Background(), &v1.UpdateWebhookRequest{
+		Id:         id,
+		URL:        "https://example.com/new",
+		EventTypes: []string{v1.EventUserCreated, v1.EventUserUpdated},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", got.URL)
+	assert.False(t, got.Active)
+
+	webhookRepo.EXPECT().GetByID(gomock.Any(), gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	_, err = s.Update(context.Background(), &v1.UpdateWebhookRequest{Id: uuid.New(), URL: "https://example.com/new", EventTypes: []string{"*"}})
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+func Test_webhookService_Deliveries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepo := mock_repository.NewMockWebhookRepository(ctrl)
+	s := newTestWebhookService(webhookRepo)
+	webhookID, deliveryID := uuid.New(), uuid.New()
+
+	webhookRepo.EXPECT().GetByID(gomock.Any(), webhookID).Return(&model.Webhook{Id: webhookID, EventTypes: "*"}, nil)
+	webhookRepo.EXPECT().ListDeliveries(gomock.Any(), repository.WebhookDeliveryFilter{
+		WebhookID: webhookID,
+		Status:    model.WebhookDeliveryFailed,
+		Limit:     DefaultWebhookPageSize,
+	}).Return([]model.WebhookDelivery{{Id: deliveryID, WebhookID: webhookID, Status: model.WebhookDeliveryFailed, Attempts: 8}}, int64(1), nil)
+
+	list, err := s.ListDeliveries(context.Background(), &v1.ListWebhookDeliveriesRequest{WebhookId: webhookID, Status: model.WebhookDeliveryFailed})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), list.Total)
+	if assert.Len(t, list.Deliveries, 1) {
+		assert.Nil(t, list.Deliveries[0].NextAttemptAt)
+	}
+
+	_, err = s.ListDeliveries(context.Background(), &v1.ListWebhookDeliveriesRequest{WebhookId: webhookID, Status: "lost"})
+	assert.ErrorIs(t, err, ErrInvalidWebhookQuery)
+	_, err = s.ListDeliveries(context.Background(), &v1.ListWebhookDeliveriesRequest{WebhookId: webhookID, Limit: MaxWebhookPageSize + 1})
+	assert.ErrorIs(t, err, ErrInvalidWebhookQuery)
+
+	webhookRepo.EXPECT().GetDelivery(gomock.Any(), webhookID, deliveryID).Return(&model.WebhookDelivery{Id: deliveryID, WebhookID: webhookID, Payload: `{"id":"evt-1"}`}, nil)
+	webhookRepo.EXPECT().ListAttempts(gomock.Any(), deliveryID).Return([]model.WebhookAttempt{
+		{Attempt: 1, StatusCode: 500, Error: "unexpected response status: 500"},
+		{Attempt: 2, StatusCode: 200},
+	}, nil)
+	detail, err := s.GetDelivery(context.Background(), webhookID, deliveryID)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"evt-1"}`, string(detail.Payload))
+	assert.Len(t, detail.History, 2)
+
+	webhookRepo.EXPECT().GetDelivery(gomock.Any(), webhookID, deliveryID).Return(&model.WebhookDelivery{Id: deliveryID, WebhookID: webhookID}, nil)
+	webhookRepo.EXPECT().Redeliver(gomock.Any(), deliveryID).Return(nil)
+	assert.NoError(t, s.Redeliver(context.Background(), webhookID, deliveryID))
+
+	// a delivery of another webhook is not found
+	otherWebhookID := uuid.New()
+	webhookRepo.EXPECT().GetDelivery(gomock.Any(), otherWebhookID, deliveryID).Return(nil, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, s.Redeliver(context.Background(), otherWebhookID, deliveryID), ErrWebhookDeliveryNotFound)
+}
+
+func Test_webhookService_Delete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepo := mock_repository.NewMockWebhookRepository(ctrl)
+	s := newTestWebhookService(webhookRepo)
+	id := uuid.New()
+
+	webhookRepo.EXPECT().Delete(gomock.Any(), id).Return(nil)
+	assert.NoError(t, s.Delete(context.Background(), id))
+
+	webhookRepo.EXPECT().Delete(gomock.Any(), id).Return(gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, s.Delete(context.Background(), id), ErrWebhookNotFound)
+
+	errDB := errors.New("database unavailable")
+	webhookRepo.EXPECT().Delete(gomock.Any(), id).Return(errDB)
+	assert.ErrorIs(t, s.Delete(context.Background(), id), errDB)
+}
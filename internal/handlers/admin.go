@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/pkg/config"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+	"github.com/giortzisg/go-boilerplate/pkg/logging"
+)
+
+var ErrInvalidLogLevel = e.NewStatusError(errors.New("invalid log level"), http.StatusBadRequest)
+
+// AdminHandler serves operational endpoints that inspect and adjust the
+// running process.
+type AdminHandler struct {
+	*Handler
+	loggers  *logging.Loggers
+	settings func() map[string]any
+}
+
+// NewAdminHandler creates an AdminHandler. loggers holds the log levels
+// that can be changed, and settings returns the effective configuration,
+// which is redacted before it is served.
+func NewAdminHandler(h *Handler, loggers *logging.Loggers, settings func() map[string]any) *AdminHandler {
+	return &AdminHandler{
+		Handler:  h,
+		loggers:  loggers,
+		settings: settings,
+	}
+}
+
+func (h *AdminHandler) BuildInfo() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return errors.New("build info is not available")
+		}
+
+		response := v1.BuildInfo{
+			GoVersion: info.GoVersion,
+			Module:    info.Main.Path,
+			Version:   info.Main.Version,
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				response.Revision = setting.Value
+			case "vcs.time":
+				response.RevisionTime = setting.Value
+			case "vcs.modified":
+				response.Modified = setting.Value == "true"
+			}
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Build info retrieved successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *AdminHandler) Config() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Config retrieved successfully",
+				Code:    http.StatusOK,
+				Data:    config.Redact(h.settings()),
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *AdminHandler) GetLogLevel() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Log level retrieved successfully",
+				Code:    http.StatusOK,
+				Data:    h.logLevels(),
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *AdminHandler) SetLogLevel() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		requestData, err := json.Decoder[v1.LogLevelRequest](r, h.decodeOptions...)
+		if err != nil {
+			return err
+		}
+
+		previous := h.loggers.Level(requestData.Package)
+		if requestData.Package != "" && strings.TrimSpace(requestData.Level) == "" {
+			h.loggers.ResetLevel(requestData.Package)
+		} else {
+			if strings.TrimSpace(requestData.Level) == "" {
+				return fmt.Errorf("%w: level is required", ErrInvalidLogLevel)
+			}
+			level, err := logging.ParseLevel(requestData.Level)
+			if err != nil {
+				return fmt.Errorf("%w: %q", ErrInvalidLogLevel, requestData.Level)
+			}
+			h.loggers.SetLevel(requestData.Package, level)
+		}
+		// logged whatever the levels are, so the change always shows up
+		h.loggers.Unleveled("handlers").InfoContext(r.Context(), "log level changed",
+			"package", requestData.Package,
+			"from", previous.String(),
+			"to", h.loggers.Level(requestData.Package).String(),
+		)
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Log level changed successfully",
+				Code:    http.StatusOK,
+				Data:    h.logLevels(),
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *AdminHandler) logLevels() v1.LogLevelResponse {
+	response := v1.LogLevelResponse{
+		Level:    h.loggers.Level("").String(),
+		Packages: map[string]string{},
+	}
+	for name, level := range h.loggers.Packages() {
+		response.Packages[name] = level.String()
+	}
+	return response
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/app"
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+)
+
+type AuditHandler struct {
+	*Handler
+	auditService app.AuditService
+}
+
+func NewAuditHandler(h *Handler, auditService app.AuditService) *AuditHandler {
+	return &AuditHandler{
+		Handler:      h,
+		auditService: auditService,
+	}
+}
+
+// List serves the audit trail, newest first. It filters on the actor_ip,
+// action, target_type and target_id query parameters and on the RFC 3339
+// times from and to, and pages with limit and offset.
+func (h *AuditHandler) List() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		query := r.URL.Query()
+		req := &v1.ListAuditRequest{
+			ActorIP:    query.Get("actor_ip"),
+			Action:     query.Get("action"),
+			TargetType: query.Get("target_type"),
+			TargetID:   query.Get("target_id"),
+		}
+
+		var err error
+		if req.From, err = parseTimeParam(query.Get("from")); err != nil {
+			return fmt.Errorf("%w: from: %v", app.ErrInvalidAuditQuery, err)
+		}
+		if req.To, err = parseTimeParam(query.Get("to")); err != nil {
+			return fmt.Errorf("%w: to: %v", app.ErrInvalidAuditQuery, err)
+		}
+		if req.Limit, err = parseIntParam(query.Get("limit")); err != nil {
+			return fmt.Errorf("%w: limit: %v", app.ErrInvalidAuditQuery, err)
+		}
+		if req.Offset, err = parseIntParam(query.Get("offset")); err != nil {
+			return fmt.Errorf("%w: offset: %v", app.ErrInvalidAuditQuery, err)
+		}
+
+		response, err := h.auditService.List(r.Context(), req)
+		if err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Audit records retrieved successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func parseIntParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/app"
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+)
+
+type AuthHandler struct {
+	*Handler
+	authService app.AuthService
+	clientIP    func(r *http.Request) string
+}
+
+// NewAuthHandler creates an AuthHandler. clientIP resolves the address
+// failed logins are counted against, so it must honor trusted proxies.
+func NewAuthHandler(h *Handler, authService app.AuthService, clientIP func(r *http.Request) string) *AuthHandler {
+	return &AuthHandler{
+		Handler:     h,
+		authService: authService,
+		clientIP:    clientIP,
+	}
+}
+
+func (h *AuthHandler) Login() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		requestData, err := json.Decoder[v1.LoginRequest](r, h.decodeOptions...)
+		if err != nil {
+			return err
+		}
+
+		response, err := h.authService.Login(r.Context(), requestData, h.clientIP(r))
+		if err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Logged in successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *AuthHandler) Unlock() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		requestData, err := json.Decoder[v1.UnlockRequest](r, h.decodeOptions...)
+		if err != nil {
+			return err
+		}
+
+		if err = h.authService.Unlock(r.Context(), requestData); err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Lockout cleared successfully",
+				Code:    http.StatusOK,
+			},
+			http.StatusOK,
+		)
+	})
+}
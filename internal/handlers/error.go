@@ -1,35 +1,65 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
 	"github.com/giortzisg/go-boilerplate/pkg/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
+// ErrTimeout is reported in place of any error caused by the request
+// context running out of time, whatever status it was wrapped with.
+var ErrTimeout = e.NewStatusError(errors.New("request timed out"), http.StatusGatewayTimeout)
+
 func ErrorHandler(f func(http.ResponseWriter, *http.Request) error) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		err := f(w, r)
 		if err != nil {
-			var status int
-			var statusErr interface {
-				error
-				HTTPStatus() int
-			}
-			if errors.As(err, &statusErr) {
-				status = statusErr.HTTPStatus()
-			}
-
-			response := &v1.Response{
-				Code:    status,
-				Message: err.Error(),
-				Data:    nil,
-			}
-
-			if err = json.Encoder(w, response, status); err != nil {
-				// if encoding fails, fallback to writing the error message directly
-				http.Error(w, err.Error(), status)
-			}
+			WriteError(w, r, err)
 		}
 	})
 }
+
+// WriteError reports err in the standard response body, using the status of
+// the first StatusError in its chain or 500 when there is none. Errors from
+// an expired deadline become ErrTimeout.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = ErrTimeout
+	}
+
+	status := http.StatusInternalServerError
+	var statusErr interface {
+		error
+		HTTPStatus() int
+	}
+	if errors.As(err, &statusErr) {
+		status = statusErr.HTTPStatus()
+	}
+
+	var retryErr interface{ RetryAfter() time.Duration }
+	if errors.As(err, &retryErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter().Seconds()))))
+	}
+
+	response := &v1.Response{
+		Code:    status,
+		Message: err.Error(),
+		Data:    nil,
+	}
+
+	err = json.Encoder(w, r, response, status)
+	if errors.Is(err, json.ErrNotAcceptable) {
+		// still report the error, in the default encoding
+		err = json.Write(w, json.Default(), response, status)
+	}
+	if err != nil {
+		// if encoding fails, fallback to writing the error message directly
+		http.Error(w, err.Error(), status)
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/giortzisg/go-boilerplate/internal/app"
+)
+
+// formatETag renders a resource version as a strong entity tag.
+func formatETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ifMatchVersion returns the version requested by the If-Match header.
+// A missing header or "*" yields zero, meaning no precondition. The weak
+// form is accepted too, since Compress weakens our tags on encoded
+// responses; foreign tags can never match one of ours, so they fail the
+// precondition.
+func ifMatchVersion(r *http.Request) (uint, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	tag, ok := strings.CutPrefix(tag, `"`)
+	if !ok {
+		return 0, app.ErrUserModified
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return 0, app.ErrUserModified
+	}
+
+	version, err := strconv.ParseUint(tag, 10, 0)
+	if err != nil || version == 0 {
+		return 0, app.ErrUserModified
+	}
+	return uint(version), nil
+}
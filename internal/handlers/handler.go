@@ -2,16 +2,21 @@ package handlers
 
 import (
 	"log/slog"
+
+	"github.com/giortzisg/go-boilerplate/pkg/json"
 )
 
 type Handler struct {
-	logger *slog.Logger
+	logger        *slog.Logger
+	decodeOptions []json.DecodeOption
 }
 
 func NewHandler(
 	logger *slog.Logger,
+	decodeOptions ...json.DecodeOption,
 ) *Handler {
 	return &Handler{
-		logger,
+		logger:        logger,
+		decodeOptions: decodeOptions,
 	}
 }
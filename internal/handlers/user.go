@@ -1,10 +1,20 @@
 package handlers
 
 import (
+	"errors"
+	"net/http"
+
 	v1 "github.com/giortzisg/go-boilerplate/api/v1"
 	"github.com/giortzisg/go-boilerplate/internal/app"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
 	"github.com/giortzisg/go-boilerplate/pkg/json"
-	"net/http"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidUserID  = e.NewStatusError(errors.New("invalid user id"), http.StatusBadRequest)
+	ErrIfMatchMissing = e.NewStatusError(errors.New("If-Match header with the user version is required"), http.StatusPreconditionRequired)
 )
 
 type UserHandler struct {
@@ -21,7 +31,7 @@ func NewUserHandler(h *Handler, userService app.UserService) *UserHandler {
 
 func (h *UserHandler) Create() http.Handler {
 	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
-		requestData, err := json.Decoder[v1.CreateUserRequest](r)
+		requestData, err := json.Decoder[v1.CreateUserRequest](r, h.decodeOptions...)
 		if err != nil {
 			return err
 		}
@@ -31,7 +41,7 @@ func (h *UserHandler) Create() http.Handler {
 		}
 
 		return json.Encoder(
-			w, &v1.Response{
+			w, r, &v1.Response{
 				Message: "User created successfully",
 				Code:    http.StatusCreated,
 			},
@@ -42,7 +52,7 @@ func (h *UserHandler) Create() http.Handler {
 
 func (h *UserHandler) GetByEmail() http.Handler {
 	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
-		requestData, err := json.Decoder[v1.GetUserByEmailRequest](r)
+		requestData, err := json.Decoder[v1.GetUserByEmailRequest](r, h.decodeOptions...)
 		if err != nil {
 			return err
 		}
@@ -52,8 +62,10 @@ func (h *UserHandler) GetByEmail() http.Handler {
 			return err
 		}
 
+		w.Header().Set("ETag", formatETag(response.Version))
+
 		return json.Encoder(
-			w,
+			w, r,
 			&v1.Response{
 				Message: "User retrieved successfully",
 				Code:    http.StatusOK,
@@ -66,20 +78,128 @@ func (h *UserHandler) GetByEmail() http.Handler {
 
 func (h *UserHandler) Update() http.Handler {
 	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
-		requestData, err := json.Decoder[v1.UpdateUserRequest](r)
+		requestData, err := json.Decoder[v1.UpdateUserRequest](r, h.decodeOptions...)
+		if err != nil {
+			return err
+		}
+
+		if requestData.Version, err = ifMatchVersion(r); err != nil {
+			return err
+		}
+
+		response, err := h.userService.Update(r.Context(), requestData)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("ETag", formatETag(response.Version))
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "User updated successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *UserHandler) Patch() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidUserID
+		}
+
+		w.Header().Set("Accept-Patch", json.MergePatchContentType+", "+json.JSONPatchContentType)
+
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			return err
+		}
+
+		patch, err := json.ReadAll(r, h.decodeOptions...)
+		if err != nil {
+			return err
+		}
+
+		current, err := h.userService.GetByID(r.Context(), &v1.GetUserByIDRequest{Id: id})
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != current.Version {
+			return app.ErrUserModified
+		}
+
+		patched, err := json.Patch(r.Header.Get("Content-Type"), &v1.GetUserResponse{
+			Name:  current.Name,
+			Email: current.Email,
+		}, patch)
 		if err != nil {
 			return err
 		}
 
-		if err = h.userService.Update(r.Context(), requestData); err != nil {
+		// The changes hold only against the representation they were
+		// resolved from, so its version is always sent as the precondition.
+		req := &v1.PatchUserRequest{Id: id, Version: current.Version}
+		if patched.Name != current.Name {
+			req.Name = &patched.Name
+		}
+		if patched.Email != current.Email {
+			req.Email = &patched.Email
+		}
+
+		response, err := h.userService.Patch(r.Context(), req)
+		if err != nil {
+			if version == 0 && errors.Is(err, app.ErrUserModified) {
+				return app.ErrUserConflict
+			}
 			return err
 		}
 
+		w.Header().Set("ETag", formatETag(response.Version))
 		return json.Encoder(
-			w,
+			w, r,
 			&v1.Response{
 				Message: "User updated successfully",
 				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *UserHandler) Delete() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			return ErrInvalidUserID
+		}
+
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			return err
+		}
+		// a delete cannot be undone, so the client must show it has seen
+		// the current version
+		if version == 0 {
+			return ErrIfMatchMissing
+		}
+
+		if err = h.userService.Delete(r.Context(), &v1.DeleteUserRequest{
+			Id:      id,
+			Version: version,
+		}); err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "User deleted successfully",
+				Code:    http.StatusOK,
 			},
 			http.StatusOK,
 		)
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/app"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidWebhookID  = e.NewStatusError(errors.New("invalid webhook id"), http.StatusBadRequest)
+	ErrInvalidDeliveryID = e.NewStatusError(errors.New("invalid webhook delivery id"), http.StatusBadRequest)
+)
+
+type WebhookHandler struct {
+	*Handler
+	webhookService app.WebhookService
+}
+
+func NewWebhookHandler(h *Handler, webhookService app.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		Handler:        h,
+		webhookService: webhookService,
+	}
+}
+
+// Create registers a webhook. The response is the only one to carry its
+// signing secret.
+func (h *WebhookHandler) Create() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		requestData, err := json.Decoder[v1.CreateWebhookRequest](r, h.decodeOptions...)
+		if err != nil {
+			return err
+		}
+
+		response, err := h.webhookService.Create(r.Context(), requestData)
+		if err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Webhook created successfully",
+				Code:    http.StatusCreated,
+				Data:    response,
+			},
+			http.StatusCreated,
+		)
+	})
+}
+
+func (h *WebhookHandler) List() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		response, err := h.webhookService.List(r.Context())
+		if err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Webhooks retrieved successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *WebhookHandler) Get() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := webhookID(r)
+		if err != nil {
+			return err
+		}
+
+		response, err := h.webhookService.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Webhook retrieved successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *WebhookHandler) Update() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := webhookID(r)
+		if err != nil {
+			return err
+		}
+
+		requestData, err := json.Decoder[v1.UpdateWebhookRequest](r, h.decodeOptions...)
+		if err != nil {
+			return err
+		}
+		requestData.Id = id
+
+		response, err := h.webhookService.Update(r.Context(), requestData)
+		if err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Webhook updated successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+func (h *WebhookHandler) Delete() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := webhookID(r)
+		if err != nil {
+			return err
+		}
+
+		if err = h.webhookService.Delete(r.Context(), id); err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Webhook deleted successfully",
+				Code:    http.StatusOK,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+// ListDeliveries serves the delivery history of a webhook, newest first.
+// It filters on the status query parameter and pages with limit and
+// offset.
+func (h *WebhookHandler) ListDeliveries() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := webhookID(r)
+		if err != nil {
+			return err
+		}
+
+		query := r.URL.Query()
+		req := &v1.ListWebhookDeliveriesRequest{
+			WebhookId: id,
+			Status:    query.Get("status"),
+		}
+		if req.Limit, err = parseIntParam(query.Get("limit")); err != nil {
+			return fmt.Errorf("%w: limit: %v", app.ErrInvalidWebhookQuery, err)
+		}
+		if req.Offset, err = parseIntParam(query.Get("offset")); err != nil {
+			return fmt.Errorf("%w: offset: %v", app.ErrInvalidWebhookQuery, err)
+		}
+
+		response, err := h.webhookService.ListDeliveries(r.Context(), req)
+		if err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Webhook deliveries retrieved successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+// GetDelivery serves a delivery with its payload and attempts.
+func (h *WebhookHandler) GetDelivery() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := webhookID(r)
+		if err != nil {
+			return err
+		}
+		deliveryID, err := uuid.Parse(chi.URLParam(r, "deliveryID"))
+		if err != nil {
+			return ErrInvalidDeliveryID
+		}
+
+		response, err := h.webhookService.GetDelivery(r.Context(), id, deliveryID)
+		if err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Webhook delivery retrieved successfully",
+				Code:    http.StatusOK,
+				Data:    response,
+			},
+			http.StatusOK,
+		)
+	})
+}
+
+// Redeliver queues a delivery to be sent again. It answers 202 since the
+// delivery happens in the background.
+func (h *WebhookHandler) Redeliver() http.Handler {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		id, err := webhookID(r)
+		if err != nil {
+			return err
+		}
+		deliveryID, err := uuid.Parse(chi.URLParam(r, "deliveryID"))
+		if err != nil {
+			return ErrInvalidDeliveryID
+		}
+
+		if err = h.webhookService.Redeliver(r.Context(), id, deliveryID); err != nil {
+			return err
+		}
+
+		return json.Encoder(
+			w, r,
+			&v1.Response{
+				Message: "Webhook delivery queued for redelivery",
+				Code:    http.StatusAccepted,
+			},
+			http.StatusAccepted,
+		)
+	})
+}
+
+func webhookID(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, ErrInvalidWebhookID
+	}
+	return id, nil
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"expvar"
+	"log/slog"
+	"net/http/pprof"
+
+	"github.com/giortzisg/go-boilerplate/internal/handlers"
+	"github.com/giortzisg/go-boilerplate/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// AdminRouter serves operational endpoints. It is meant for a listener of
+// its own so none of these routes are reachable through the public Router.
+type AdminRouter struct {
+	*chi.Mux
+	adminHandler   *handlers.AdminHandler
+	auditHandler   *handlers.AuditHandler
+	webhookHandler *handlers.WebhookHandler
+	userHandler    *handlers.UserHandler
+	profiling      bool
+	expvar         bool
+}
+
+type AdminOption func(r *AdminRouter)
+
+// WithAdminHandler serves build info, the redacted effective config and
+// the log level, which can be changed with a PUT.
+func WithAdminHandler(adminHandler *handlers.AdminHandler) AdminOption {
+	return func(r *AdminRouter) {
+		r.adminHandler = adminHandler
+	}
+}
+
+// WithAudit serves the audit trail at /audit.
+func WithAudit(auditHandler *handlers.AuditHandler) AdminOption {
+	return func(r *AdminRouter) {
+		r.auditHandler = auditHandler
+	}
+}
+
+// WithWebhooks serves the webhook registration and delivery history API
+// under /webhooks. Webhooks choose where the server sends requests, so they
+// are managed from here rather than through the public Router.
+func WithWebhooks(webhookHandler *handlers.WebhookHandler) AdminOption {
+	return func(r *AdminRouter) {
+		r.webhookHandler = webhookHandler
+	}
+}
+
+// WithUsers serves DELETE /users/{id}. The public Router has no
+// authentication, so deleting users is only offered here.
+func WithUsers(userHandler *handlers.UserHandler) AdminOption {
+	return func(r *AdminRouter) {
+		r.userHandler = userHandler
+	}
+}
+
+// WithProfiling serves the net/http/pprof profiles under /debug/pprof/.
+func WithProfiling() AdminOption {
+	return func(r *AdminRouter) {
+		r.profiling = true
+	}
+}
+
+// WithExpvar serves the published expvar variables at /debug/vars.
+func WithExpvar() AdminOption {
+	return func(r *AdminRouter) {
+		r.expvar = true
+	}
+}
+
+// NewAdminRouter creates the admin router. authHandler may be nil when
+// logins are not served.
+func NewAdminRouter(logger *slog.Logger, authHandler *handlers.AuthHandler, opts ...AdminOption) *AdminRouter {
+	router := &AdminRouter{
+		Mux: chi.NewRouter(),
+	}
+
+	for _, opt := range opts {
+		opt(router)
+	}
+
+	router.Use(middleware.Recover(logger))
+	router.Handle("/metrics", promhttp.Handler())
+	router.Group(func(r chi.Router) {
+		// only the JSON endpoints negotiate; metrics and profiles have
+		// their own formats
+		r.Use(middleware.Negotiate())
+		if authHandler != nil {
+			r.Post("/auth/unlock", authHandler.Unlock().ServeHTTP)
+		}
+		if router.adminHandler != nil {
+			r.Get("/buildinfo", router.adminHandler.BuildInfo().ServeHTTP)
+			r.Get("/config", router.adminHandler.Config().ServeHTTP)
+			r.Get("/loglevel", router.adminHandler.GetLogLevel().ServeHTTP)
+			r.Put("/loglevel", router.adminHandler.SetLogLevel().ServeHTTP)
+		}
+		if router.auditHandler != nil {
+			r.Get("/audit", router.auditHandler.List().ServeHTTP)
+		}
+		if router.userHandler != nil {
+			r.Delete("/users/{id}", router.userHandler.Delete().ServeHTTP)
+		}
+		if router.webhookHandler != nil {
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Post("/", router.webhookHandler.Create().ServeHTTP)
+				r.Get("/", router.webhookHandler.List().ServeHTTP)
+				r.Get("/{id}", router.webhookHandler.Get().ServeHTTP)
+				r.Put("/{id}", router.webhookHandler.Update().ServeHTTP)
+				r.Delete("/{id}", router.webhookHandler.Delete().ServeHTTP)
+				r.Get("/{id}/deliveries", router.webhookHandler.ListDeliveries().ServeHTTP)
+				r.Get("/{id}/deliveries/{deliveryID}", router.webhookHandler.GetDelivery().ServeHTTP)
+				r.Post("/{id}/deliveries/{deliveryID}/redeliver", router.webhookHandler.Redeliver().ServeHTTP)
+			})
+		}
+	})
+	if router.profiling {
+		// Index also serves the named profiles, such as heap and goroutine
+		router.HandleFunc("/debug/pprof/*", pprof.Index)
+		router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if router.expvar {
+		router.Handle("/debug/vars", expvar.Handler())
+	}
+	return router
+}
@@ -1,34 +1,312 @@
 package http
 
 import (
+	"errors"
+	"fmt"
 	"github.com/giortzisg/go-boilerplate/internal/handlers"
 	"github.com/giortzisg/go-boilerplate/internal/middleware"
+	"github.com/giortzisg/go-boilerplate/pkg/health"
+	"github.com/giortzisg/go-boilerplate/pkg/ratelimit"
 	"github.com/go-chi/chi/v5"
 	"log/slog"
+	"net/http"
+	"time"
 )
 
 type Router struct {
 	*chi.Mux
-	userHandler handlers.UserHandler
+	logger           *slog.Logger
+	userHandler      handlers.UserHandler
+	authHandler      *handlers.AuthHandler
+	accessLogOptions []middleware.LoggingOption
+	health           *health.Registry
+	rateLimit        RateLimitConfig
+	cors             *CORSConfig
+	compress         bool
+	timeout          *TimeoutConfig
+	idempotency      *middleware.IdempotencyConfig
+	auditActor       bool
+	trustedProxies   middleware.TrustedProxies
+	compressOptions  []middleware.CompressOption
 }
 
-func NewRouter(logger *slog.Logger, userHandler handlers.UserHandler) *Router {
+// CORSConfig sets up CORS. Routes listed in Routes, keyed by their chi
+// pattern such as "/users/{id}", use their own policy instead of Default.
+type CORSConfig struct {
+	Default middleware.CORSConfig
+	Routes  map[string]middleware.CORSConfig
+}
+
+// RateLimitConfig sets up rate limiting. Routes use Default, except for
+// user sign-ups and logins which use their own limit when it is valid.
+type RateLimitConfig struct {
+	Store      ratelimit.Store
+	Key        middleware.KeyFunc
+	Default    ratelimit.Limit
+	CreateUser ratelimit.Limit
+	Login      ratelimit.Limit
+}
+
+// or returns limit, or Default when limit is not valid.
+func (c RateLimitConfig) or(limit ratelimit.Limit) ratelimit.Limit {
+	if limit.Valid() {
+		return limit
+	}
+	return c.Default
+}
+
+type Option func(r *Router)
+
+// WithAccessLog configures the access log middleware of the router.
+func WithAccessLog(opts ...middleware.LoggingOption) Option {
+	return func(r *Router) {
+		r.accessLogOptions = append(r.accessLogOptions, opts...)
+	}
+}
+
+// WithHealth serves /healthz and /readyz from registry.
+func WithHealth(registry *health.Registry) Option {
+	return func(r *Router) {
+		r.health = registry
+	}
+}
+
+// WithAuth serves the login endpoint.
+func WithAuth(authHandler *handlers.AuthHandler) Option {
+	return func(r *Router) {
+		r.authHandler = authHandler
+	}
+}
+
+// TimeoutConfig bounds how long requests may take. Routes listed in Routes,
+// keyed by their chi pattern, use their own timeout instead of Default. A
+// timeout of zero disables it for the route.
+type TimeoutConfig struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// WithTimeout cancels the context of requests that run past their timeout.
+func WithTimeout(conf TimeoutConfig) Option {
+	return func(r *Router) {
+		r.timeout = &conf
+	}
+}
+
+// WithIdempotency lets clients retry unsafe requests with an
+// Idempotency-Key and get the original response back.
+func WithIdempotency(conf middleware.IdempotencyConfig) Option {
+	return func(r *Router) {
+		r.idempotency = &conf
+	}
+}
+
+// WithCompression compresses responses for clients that accept it.
+func WithCompression(opts ...middleware.CompressOption) Option {
+	return func(r *Router) {
+		r.compress = true
+		r.compressOptions = append(r.compressOptions, opts...)
+	}
+}
+
+// WithCORS enables CORS handling, including preflight requests.
+func WithCORS(conf CORSConfig) Option {
+	return func(r *Router) {
+		r.cors = &conf
+	}
+}
+
+// WithAuditActor makes the client of each request the actor of the audit
+// records written while serving it. trustedProxies resolves its address.
+func WithAuditActor(trustedProxies middleware.TrustedProxies) Option {
+	return func(r *Router) {
+		r.auditActor = true
+		r.trustedProxies = trustedProxies
+	}
+}
+
+// WithRateLimit enables per-client rate limiting.
+func WithRateLimit(conf RateLimitConfig) Option {
+	return func(r *Router) {
+		r.rateLimit = conf
+	}
+}
+
+func NewRouter(logger *slog.Logger, userHandler handlers.UserHandler, opts ...Option) *Router {
 	router := &Router{
 		Mux:         chi.NewRouter(),
+		logger:      logger,
 		userHandler: userHandler,
 	}
 
-	router.Use(middleware.Logging(logger))
+	for _, opt := range opts {
+		opt(router)
+	}
+
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Metrics())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.Logging(logger, router.accessLogOptions...))
+	router.Use(middleware.Recover(logger))
+	// CORS goes before Negotiate so that browsers can read its 406 and 415
+	// responses
+	if router.cors != nil {
+		router.Use(router.corsMiddleware())
+	}
+	router.Use(middleware.Negotiate())
+	if router.auditActor {
+		router.Use(middleware.AuditActor(router.trustedProxies))
+	}
+	if router.compress {
+		router.Use(middleware.Compress(router.compressOptions...))
+	}
+	if router.timeout != nil {
+		router.Use(router.timeoutMiddleware())
+	}
+	if router.idempotency != nil {
+		router.Use(middleware.Idempotency(logger, *router.idempotency))
+	}
+	router.RegisterHealthRoutes()
 	router.RegisterUserRoutes()
+	router.RegisterAuthRoutes()
 	return router
 }
 
+func (r *Router) RegisterHealthRoutes() {
+	if r.health == nil {
+		return
+	}
+
+	r.Get("/healthz", r.health.LivenessHandler().ServeHTTP)
+	r.Get("/readyz", r.health.ReadinessHandler().ServeHTTP)
+}
+
 func (r *Router) RegisterUserRoutes() {
 	r.userHandler.Create()
 
 	r.Route("/users", func(chi chi.Router) {
-		chi.Post("/", r.userHandler.Create().ServeHTTP)
-		chi.Get("/", r.userHandler.GetByEmail().ServeHTTP)
-		chi.Put("/", r.userHandler.Update().ServeHTTP)
+		chi.With(r.limit(r.rateLimit.or(r.rateLimit.CreateUser))).Post("/", r.userHandler.Create().ServeHTTP)
+		chi.With(r.limit(r.rateLimit.Default)).Get("/", r.userHandler.GetByEmail().ServeHTTP)
+		chi.With(r.limit(r.rateLimit.Default)).Put("/", r.userHandler.Update().ServeHTTP)
+		chi.With(r.limit(r.rateLimit.Default)).Patch("/{id}", r.userHandler.Patch().ServeHTTP)
 	})
 }
+
+func (r *Router) RegisterAuthRoutes() {
+	if r.authHandler == nil {
+		return
+	}
+
+	r.Route("/auth", func(chi chi.Router) {
+		chi.With(r.limit(r.rateLimit.or(r.rateLimit.Login))).Post("/login", r.authHandler.Login().ServeHTTP)
+	})
+}
+
+// limit returns the rate limiting middleware for a route, or a pass-through
+// when rate limiting is not configured.
+func (r *Router) limit(limit ratelimit.Limit) func(next http.Handler) http.Handler {
+	if r.rateLimit.Store == nil || !limit.Valid() {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	key := r.rateLimit.Key
+	if key == nil {
+		key = middleware.KeyByIP(nil)
+	}
+	return middleware.RateLimit(r.logger, r.rateLimit.Store, limit, key)
+}
+
+// corsMiddleware applies the CORS policy of the route a request is for. It
+// runs before routing so that preflights are answered instead of hitting a
+// 405, which means the route is looked up by the method being preflighted.
+func (r *Router) corsMiddleware() func(next http.Handler) http.Handler {
+	defaultPolicy := middleware.CORS(r.cors.Default)
+	routePolicies := make(map[string]func(next http.Handler) http.Handler, len(r.cors.Routes))
+	for pattern, conf := range r.cors.Routes {
+		routePolicies[pattern] = middleware.CORS(conf)
+	}
+
+	return func(next http.Handler) http.Handler {
+		defaultHandler := defaultPolicy(next)
+		routeHandlers := make(map[string]http.Handler, len(routePolicies))
+		for pattern, policy := range routePolicies {
+			routeHandlers[pattern] = policy(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			method := req.Method
+			if middleware.IsPreflight(req) {
+				method = req.Header.Get("Access-Control-Request-Method")
+			}
+
+			if handler, ok := routeHandlers[r.routePattern(method, req.URL.Path)]; ok {
+				handler.ServeHTTP(w, req)
+				return
+			}
+			defaultHandler.ServeHTTP(w, req)
+		})
+	}
+}
+
+// timeoutMiddleware applies the timeout of the route a request is for.
+func (r *Router) timeoutMiddleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		defaultHandler := middleware.Timeout(r.timeout.Default)(next)
+		routeHandlers := make(map[string]http.Handler, len(r.timeout.Routes))
+		for pattern, timeout := range r.timeout.Routes {
+			routeHandlers[pattern] = middleware.Timeout(timeout)(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if handler, ok := routeHandlers[r.routePattern(req.Method, req.URL.Path)]; ok {
+				handler.ServeHTTP(w, req)
+				return
+			}
+			defaultHandler.ServeHTTP(w, req)
+		})
+	}
+}
+
+// Validate reports the route overrides whose pattern is not the pattern of
+// a registered route. They would never apply, most likely because of a
+// typo in the config.
+func (r *Router) Validate() error {
+	var errs []error
+	if r.cors != nil {
+		for pattern := range r.cors.Routes {
+			if !r.registered(pattern) {
+				errs = append(errs, fmt.Errorf("cors: no route matches pattern %q", pattern))
+			}
+		}
+	}
+	if r.timeout != nil {
+		for pattern := range r.timeout.Routes {
+			if !r.registered(pattern) {
+				errs = append(errs, fmt.Errorf("request timeout: no route matches pattern %q", pattern))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registered reports whether pattern is the pattern of a route for any
+// method. A pattern matches itself, its parameters taking their own names
+// as values.
+func (r *Router) registered(pattern string) bool {
+	for _, method := range []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	} {
+		if r.routePattern(method, pattern) == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// routePattern looks up the pattern of the route serving method and path,
+// for middlewares that need it before chi has routed the request.
+func (r *Router) routePattern(method, path string) string {
+	return r.Find(chi.NewRouteContext(), method, path)
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/internal/app"
+	"github.com/giortzisg/go-boilerplate/internal/handlers"
+	"github.com/giortzisg/go-boilerplate/internal/middleware"
+	"github.com/google/uuid"
+)
+
+// userService records deletes and serves nothing else.
+type userService struct {
+	app.UserService
+	deleted []uuid.UUID
+}
+
+func (s *userService) Delete(_ context.Context, req *v1.DeleteUserRequest) error {
+	s.deleted = append(s.deleted, req.Id)
+	return nil
+}
+
+func TestDeleteUser_OnlyOnAdminRouter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := &userService{}
+	userHandler := handlers.NewUserHandler(handlers.NewHandler(logger), service)
+	id := uuid.New()
+
+	deleteUser := func(h http.Handler) int {
+		r := httptest.NewRequest(http.MethodDelete, "/users/"+id.String(), nil)
+		r.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	public := NewRouter(logger, *userHandler)
+	if code := deleteUser(public); code != http.StatusMethodNotAllowed {
+		t.Errorf("public DELETE status = %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+	if len(service.deleted) != 0 {
+		t.Fatalf("an anonymous public request deleted %v", service.deleted)
+	}
+
+	admin := NewAdminRouter(logger, nil, WithUsers(userHandler))
+	if code := deleteUser(admin); code != http.StatusOK {
+		t.Errorf("admin DELETE status = %d, want %d", code, http.StatusOK)
+	}
+	if len(service.deleted) != 1 || service.deleted[0] != id {
+		t.Errorf("deleted %v, want [%s]", service.deleted, id)
+	}
+}
+
+func TestNegotiateErrors_CarryCORSHeaders(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	userHandler := handlers.NewUserHandler(handlers.NewHandler(logger), &userService{})
+	router := NewRouter(logger, *userHandler, WithCORS(CORSConfig{
+		Default: middleware.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+	}))
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+	}{
+		{name: "not acceptable", header: http.Header{"Accept": {"application/xml"}}, wantStatus: http.StatusNotAcceptable},
+		{name: "unsupported media type", header: http.Header{"Content-Type": {"application/xml"}}, wantStatus: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users/", strings.NewReader("<user/>"))
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+			r.Header.Set("Origin", "https://app.example.com")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want the request origin", got)
+			}
+		})
+	}
+}
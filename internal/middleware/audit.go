@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/giortzisg/go-boilerplate/pkg/audit"
+)
+
+// AuditActor makes the client of a request the actor of the audit records
+// written while serving it. Requests are not authenticated, so the actor is
+// only the client address, resolved through trustedProxies.
+func AuditActor(trustedProxies TrustedProxies) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := audit.WithActor(r.Context(), audit.Actor{IP: trustedProxies.ClientIP(r)})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
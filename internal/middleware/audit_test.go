@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giortzisg/go-boilerplate/pkg/audit"
+)
+
+func TestAuditActor(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("failed to parse trusted proxies: %v", err)
+	}
+
+	var got audit.Actor
+	handler := AuditActor(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = audit.ActorFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if want := (audit.Actor{IP: "198.51.100.1"}); got != want {
+		t.Errorf("actor = %+v, want %+v", got, want)
+	}
+}
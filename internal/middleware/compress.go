@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/giortzisg/go-boilerplate/pkg/compress"
+)
+
+// DefaultCompressMinSize is the smallest body worth compressing; below it
+// the encoding overhead outweighs the savings.
+const DefaultCompressMinSize = 1024
+
+// DefaultCompressibleTypes are the media types compressed unless
+// WithCompressibleTypes says otherwise.
+var DefaultCompressibleTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"application/msgpack",
+	"application/cbor",
+	"text/*",
+}
+
+type compressOptions struct {
+	minSize   int
+	types     []string
+	encodings []string
+}
+
+type CompressOption func(o *compressOptions)
+
+// WithMinSize leaves bodies smaller than size bytes uncompressed.
+func WithMinSize(size int) CompressOption {
+	return func(o *compressOptions) {
+		o.minSize = size
+	}
+}
+
+// WithCompressibleTypes sets the media types that are compressed. A type
+// may end in "/*" to cover all of its subtypes.
+func WithCompressibleTypes(types ...string) CompressOption {
+	return func(o *compressOptions) {
+		o.types = types
+	}
+}
+
+// WithEncodings sets the content codings offered, most preferred first.
+// Names that pkg/compress does not know are ignored.
+func WithEncodings(encodings ...string) CompressOption {
+	return func(o *compressOptions) {
+		o.encodings = encodings
+	}
+}
+
+func (o *compressOptions) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range o.types {
+		allowed = strings.ToLower(allowed)
+		if allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Compress encodes response bodies with the best content coding the client
+// accepts. Bodies are buffered until they reach the minimum size, so small
+// responses go out as they are, and responses that are already encoded or
+// have a media type outside the allowlist are never touched.
+func Compress(opts ...CompressOption) func(next http.Handler) http.Handler {
+	o := &compressOptions{
+		minSize:   DefaultCompressMinSize,
+		types:     DefaultCompressibleTypes,
+		encodings: compress.DefaultPreference,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var encodings []string
+	for _, name := range o.encodings {
+		if _, ok := compress.Lookup(name); ok {
+			encodings = append(encodings, name)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			name := compress.Negotiate(r.Header.Get("Accept-Encoding"), encodings)
+			if name == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			encoding, _ := compress.Lookup(name)
+			cw := &compressWriter{ResponseWriter: w, options: o, encoding: encoding}
+			next.ServeHTTP(cw, r)
+			// skipped on panic, so Recover can still answer with a 500 when
+			// nothing has been sent yet
+			cw.close()
+		})
+	}
+}
+
+// compressWriter holds back the start of a response until it knows whether
+// the body is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	options  *compressOptions
+	encoding compress.Encoding
+	status   int
+	buf      []byte
+	decided  bool
+	writer   compress.Writer
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.decided {
+		return cw.write(b)
+	}
+
+	header := cw.Header()
+	if header.Get("Content-Type") == "" {
+		// sniff now, net/http would otherwise sniff the compressed bytes
+		header.Set("Content-Type", http.DetectContentType(b))
+	}
+	if !cw.shouldCompress() {
+		cw.start(false)
+		return cw.write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) < cw.options.minSize {
+		return len(b), nil
+	}
+	if err := cw.flushBuffer(true); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (cw *compressWriter) write(b []byte) (int, error) {
+	if cw.writer != nil {
+		return cw.writer.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressWriter) shouldCompress() bool {
+	switch cw.status {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	header := cw.Header()
+	return header.Get("Content-Encoding") == "" && cw.options.compressible(header.Get("Content-Type"))
+}
+
+// start sends the header, switching to the encoder when compressing. A
+// strong ETag is weakened then, as the encoded bytes differ from the
+// representation it was computed for.
+func (cw *compressWriter) start(compressed bool) {
+	cw.decided = true
+	if compressed {
+		header := cw.Header()
+		header.Set("Content-Encoding", cw.encoding.Name())
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.writer = cw.encoding.NewWriter(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+func (cw *compressWriter) flushBuffer(compressed bool) error {
+	cw.start(compressed)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := cw.write(buf)
+	return err
+}
+
+// FlushError lets streaming handlers push out what they have written. The
+// body is compressed from then on even if it never reaches the minimum size.
+func (cw *compressWriter) FlushError() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.flushBuffer(cw.shouldCompress()); err != nil {
+			return err
+		}
+	}
+	if cw.writer != nil {
+		if err := cw.writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Flush() {
+	_ = cw.FlushError()
+}
+
+func (cw *compressWriter) close() {
+	if !cw.decided && cw.status != 0 {
+		// the whole body fit under the minimum size
+		_ = cw.flushBuffer(false)
+	}
+	if cw.writer != nil {
+		_ = cw.writer.Close()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/giortzisg/go-boilerplate/pkg/compress"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"name":"Test User"}`, 100)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		status         int
+		wantEncoding   string
+	}{
+		{name: "gzip", acceptEncoding: "gzip", contentType: "application/json", body: large, wantEncoding: compress.Gzip},
+		{name: "deflate", acceptEncoding: "deflate", contentType: "application/json", body: large, wantEncoding: compress.Deflate},
+		{name: "brotli", acceptEncoding: "br", contentType: "application/json", body: large, wantEncoding: compress.Brotli},
+		{name: "zstd preferred", acceptEncoding: "gzip, br, zstd", contentType: "application/json", body: large, wantEncoding: compress.Zstd},
+		{name: "wildcard subtype allowed", acceptEncoding: "gzip", contentType: "text/csv; charset=utf-8", body: large, wantEncoding: compress.Gzip},
+		{name: "below minimum size", acceptEncoding: "gzip", contentType: "application/json", body: `{"name":"Test User"}`},
+		{name: "type not allowed", acceptEncoding: "gzip", contentType: "image/png", body: large},
+		{name: "not accepted", acceptEncoding: "", contentType: "application/json", body: large},
+		{name: "error bodies are compressed", acceptEncoding: "gzip", contentType: "application/json", body: large, status: http.StatusBadRequest, wantEncoding: compress.Gzip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(status)
+				// several writes, so buffering across them is exercised
+				for _, chunk := range strings.SplitAfter(tt.body, "}") {
+					_, _ = io.WriteString(w, chunk)
+				}
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != status {
+				t.Errorf("status = %d, want %d", w.Code, status)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+
+			body, err := compress.NewReader(tt.wantEncoding, w.Body)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestCompress_ETag(t *testing.T) {
+	large := strings.Repeat(`{"name":"Test User"}`, 100)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		etag           string
+		want           string
+	}{
+		{name: "strong tag weakened", acceptEncoding: "gzip", etag: `"3"`, want: `W/"3"`},
+		{name: "weak tag kept", acceptEncoding: "gzip", etag: `W/"3"`, want: `W/"3"`},
+		{name: "uncompressed tag kept", etag: `"3"`, want: `"3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("ETag", tt.etag)
+				_, _ = io.WriteString(w, large)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Header().Get("ETag"); got != tt.want {
+				t.Errorf("ETag = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompress_NoContent(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	r := httptest.NewRequest(http.MethodDelete, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("got status %d, %d bytes, Content-Encoding %q", w.Code, w.Body.Len(), w.Header().Get("Content-Encoding"))
+	}
+}
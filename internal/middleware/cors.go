@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCORSMethods are allowed when a CORSConfig lists no methods.
+var DefaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// CORSConfig describes which cross-origin requests browsers may make.
+// AllowedOrigins holds exact origins such as "https://app.example.com",
+// wildcard subdomains such as "https://*.example.com", or "*" for any.
+// AllowedHeaders may be "*" to accept whatever the browser asks for.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type corsPolicy struct {
+	CORSConfig
+	anyOrigin bool
+	anyHeader bool
+	methods   []string
+	headers   []string
+}
+
+func newCORSPolicy(conf CORSConfig) *corsPolicy {
+	p := &corsPolicy{CORSConfig: conf, methods: conf.AllowedMethods}
+	if len(p.methods) == 0 {
+		p.methods = DefaultCORSMethods
+	}
+	for _, origin := range conf.AllowedOrigins {
+		if origin == "*" {
+			p.anyOrigin = true
+		}
+	}
+	for _, header := range conf.AllowedHeaders {
+		if header == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers = append(p.headers, http.CanonicalHeaderKey(header))
+	}
+	return p
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == origin {
+			return true
+		}
+		// "https://*.example.com" matches any subdomain, not the apex
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok &&
+			len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *corsPolicy) allowMethod(method string) bool {
+	return slices.Contains(p.methods, strings.ToUpper(method))
+}
+
+func (p *corsPolicy) allowHeaders(requested []string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range requested {
+		if !slices.Contains(p.headers, http.CanonicalHeaderKey(header)) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowOriginValue is what Access-Control-Allow-Origin is set to. Browsers
+// refuse "*" on credentialed requests, so the origin is echoed instead.
+func (p *corsPolicy) allowOriginValue(origin string) string {
+	if p.anyOrigin && !p.AllowCredentials {
+		return "*"
+	}
+	return origin
+}
+
+// variesByOrigin reports whether responses differ between origins, which
+// is always the case unless every origin gets the same "*".
+func (p *corsPolicy) variesByOrigin() bool {
+	return !p.anyOrigin || p.AllowCredentials
+}
+
+func parseHeaderList(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// IsPreflight reports whether r is a CORS preflight request.
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// CORS answers preflight requests with a 204 and adds the CORS response
+// headers to actual requests from allowed origins. Requests from other
+// origins are served without CORS headers, so the browser blocks them.
+func CORS(conf CORSConfig) func(next http.Handler) http.Handler {
+	p := newCORSPolicy(conf)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if IsPreflight(r) {
+				p.preflight(w, r)
+				return
+			}
+
+			origin := r.Header.Get("Origin")
+			if p.variesByOrigin() {
+				w.Header().Add("Vary", "Origin")
+			}
+			if origin != "" && p.allowOrigin(origin) {
+				w.Header().Set("Access-Control-Allow-Origin", p.allowOriginValue(origin))
+				if p.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				if len(p.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+	requested := parseHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+	if !p.allowOrigin(origin) || !p.allowMethod(method) || !p.allowHeaders(requested) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", p.allowOriginValue(origin))
+	header.Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+	if len(requested) > 0 {
+		if p.anyHeader {
+			header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+		} else {
+			header.Set("Access-Control-Allow-Headers", strings.Join(p.headers, ", "))
+		}
+	}
+	if p.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if p.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	conf := CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders: []string{"Content-Type", "If-Match"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	tests := []struct {
+		name           string
+		conf           CORSConfig
+		method         string
+		header         map[string]string
+		wantStatus     int
+		wantOrigin     string
+		wantHeaders    map[string]string
+		wantVaryOrigin bool
+	}{
+		{
+			name:           "exact origin",
+			conf:           conf,
+			method:         http.MethodGet,
+			header:         map[string]string{"Origin": "https://app.example.com"},
+			wantStatus:     http.StatusOK,
+			wantOrigin:     "https://app.example.com",
+			wantHeaders:    map[string]string{"Access-Control-Expose-Headers": "ETag"},
+			wantVaryOrigin: true,
+		},
+		{
+			name:           "wildcard subdomain",
+			conf:           conf,
+			method:         http.MethodGet,
+			header:         map[string]string{"Origin": "https://eu.api.example.org"},
+			wantStatus:     http.StatusOK,
+			wantOrigin:     "https://eu.api.example.org",
+			wantVaryOrigin: true,
+		},
+		{
+			name:           "wildcard does not match the apex domain",
+			conf:           conf,
+			method:         http.MethodGet,
+			header:         map[string]string{"Origin": "https://example.org"},
+			wantStatus:     http.StatusOK,
+			wantVaryOrigin: true,
+		},
+		{
+			name:           "request without origin still varies",
+			conf:           conf,
+			method:         http.MethodGet,
+			wantStatus:     http.StatusOK,
+			wantVaryOrigin: true,
+		},
+		{
+			name:   "preflight",
+			conf:   conf,
+			method: http.MethodOptions,
+			header: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "content-type, if-match",
+			},
+			wantStatus: http.StatusNoContent,
+			wantOrigin: "https://app.example.com",
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Methods": "GET, PUT",
+				"Access-Control-Allow-Headers": "Content-Type, If-Match",
+				"Access-Control-Max-Age":       "600",
+			},
+			wantVaryOrigin: true,
+		},
+		{
+			name:   "preflight with a disallowed header",
+			conf:   conf,
+			method: http.MethodOptions,
+			header: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "X-Secret",
+			},
+			wantStatus:     http.StatusNoContent,
+			wantVaryOrigin: true,
+		},
+		{
+			name:   "preflight with a disallowed method",
+			conf:   conf,
+			method: http.MethodOptions,
+			header: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			wantStatus:     http.StatusNoContent,
+			wantVaryOrigin: true,
+		},
+		{
+			name:       "any origin",
+			conf:       CORSConfig{AllowedOrigins: []string{"*"}},
+			method:     http.MethodGet,
+			header:     map[string]string{"Origin": "https://elsewhere.test"},
+			wantStatus: http.StatusOK,
+			wantOrigin: "*",
+		},
+		{
+			name:           "any origin with credentials echoes the origin",
+			conf:           CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method:         http.MethodGet,
+			header:         map[string]string{"Origin": "https://elsewhere.test"},
+			wantStatus:     http.StatusOK,
+			wantOrigin:     "https://elsewhere.test",
+			wantHeaders:    map[string]string{"Access-Control-Allow-Credentials": "true"},
+			wantVaryOrigin: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CORS(tt.conf)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(tt.method, "/users/", nil)
+			for key, value := range tt.header {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			for key, want := range tt.wantHeaders {
+				if got := w.Header().Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			varyOrigin := false
+			for _, value := range w.Header().Values("Vary") {
+				varyOrigin = varyOrigin || value == "Origin"
+			}
+			if varyOrigin != tt.wantVaryOrigin {
+				t.Errorf("Vary = %v, want Origin listed: %v", w.Header().Values("Vary"), tt.wantVaryOrigin)
+			}
+		})
+	}
+}
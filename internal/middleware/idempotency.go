@@ -0,0 +1,229 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/handlers"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+	"github.com/giortzisg/go-boilerplate/pkg/idempotency"
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+)
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrIdempotencyKeyInvalid  = e.NewStatusError(errors.New("invalid idempotency key"), http.StatusBadRequest)
+	ErrIdempotencyKeyMismatch = e.NewStatusError(errors.New("idempotency key was already used for a different request"), http.StatusUnprocessableEntity)
+	ErrIdempotencyKeyInFlight = e.NewStatusError(errors.New("a request with this idempotency key is still in progress"), http.StatusConflict)
+)
+
+// IdempotencyConfig sets up Idempotency. Key scopes idempotency keys to a
+// client so clients cannot replay each other's responses. Lease bounds how
+// long a running request holds its key and should outlast the request
+// timeout; TTL is how long the response is replayed after. They default to
+// idempotency.DefaultLease and idempotency.DefaultTTL, and MaxBodySize to
+// json.DefaultMaxBodySize.
+type IdempotencyConfig struct {
+	Store       idempotency.Store
+	Key         KeyFunc
+	Lease       time.Duration
+	TTL         time.Duration
+	MaxBodySize int64
+}
+
+// Idempotency makes unsafe requests carrying an Idempotency-Key safe to
+// retry. The first request with a key runs normally and its response is
+// stored; retries get that response replayed, marked Idempotent-Replayed.
+// Reusing a key for a different request answers 422, and retrying while
+// the first request is still running answers 409. Server errors are not
+// stored, so such requests can be retried for real.
+func Idempotency(logger *slog.Logger, conf IdempotencyConfig) func(next http.Handler) http.Handler {
+	if conf.Lease <= 0 {
+		conf.Lease = idempotency.DefaultLease
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = idempotency.DefaultTTL
+	}
+	if conf.MaxBodySize == 0 {
+		conf.MaxBodySize = json.DefaultMaxBodySize
+	}
+	if conf.Key == nil {
+		conf.Key = KeyByIP(nil)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(idempotency.Header)
+			if idempotencyKey == "" || isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				handlers.WriteError(w, r, fmt.Errorf("%w: longer than %d characters", ErrIdempotencyKeyInvalid, maxIdempotencyKeyLength))
+				return
+			}
+
+			body, err := readBody(r, conf.MaxBodySize)
+			if err != nil {
+				handlers.WriteError(w, r, err)
+				return
+			}
+
+			sum := sha256.Sum256([]byte(conf.Key(r) + "\n" + idempotencyKey))
+			key := hex.EncodeToString(sum[:])
+			fingerprint := idempotency.Fingerprint(r.Method, r.URL.RequestURI(), r.Header, body)
+
+			record, claimed, err := conf.Store.Begin(r.Context(), key, fingerprint, conf.Lease)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "idempotency store failed", "error", err)
+				handlers.WriteError(w, r, ErrServiceUnavailable)
+				return
+			}
+			if !claimed {
+				switch {
+				case record.Fingerprint != fingerprint:
+					handlers.WriteError(w, r, ErrIdempotencyKeyMismatch)
+				case record.InFlight():
+					w.Header().Set("Retry-After", "1")
+					handlers.WriteError(w, r, ErrIdempotencyKeyInFlight)
+				default:
+					replay(w, record.Response)
+				}
+				return
+			}
+
+			rec := &recordingWriter{ResponseWriter: w}
+			// the outcome is stored even when the request was cancelled
+			ctx := context.WithoutCancel(r.Context())
+			finished := false
+			defer func() {
+				if finished {
+					return
+				}
+				// the handler panicked, let the request be retried
+				if err := conf.Store.Release(ctx, key); err != nil {
+					logger.ErrorContext(ctx, "idempotency store failed", "error", err)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+			finished = true
+
+			response := rec.response()
+			if !storable(response.Status) {
+				if err := conf.Store.Release(ctx, key); err != nil {
+					logger.ErrorContext(ctx, "idempotency store failed", "error", err)
+				}
+				return
+			}
+			if err := conf.Store.Complete(ctx, key, response, conf.TTL); err != nil {
+				logger.ErrorContext(ctx, "idempotency store failed", "error", err)
+				// better to run a retry again than to keep it in flight
+				if err := conf.Store.Release(ctx, key); err != nil {
+					logger.ErrorContext(ctx, "idempotency store failed", "error", err)
+				}
+			}
+		})
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// storable reports whether a response is final. Server errors and the
+// statuses asking the client to come back later are not.
+func storable(status int) bool {
+	switch {
+	case status == 0, status >= http.StatusInternalServerError:
+		return false
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
+
+// readBody reads the raw request body for the fingerprint and puts it back
+// for the handler.
+func readBody(r *http.Request, maxBodySize int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	reader := io.Reader(r.Body)
+	if maxBodySize > 0 {
+		reader = http.MaxBytesReader(nil, r.Body, maxBodySize)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, e.NewStatusError(fmt.Errorf("%w: limit is %d bytes", json.ErrBodyTooLarge, maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+		}
+		return nil, e.NewStatusError(fmt.Errorf("%w: %v", json.ErrInvalidBody, err), http.StatusBadRequest)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func replay(w http.ResponseWriter, response *idempotency.Response) {
+	header := w.Header()
+	for key, values := range response.Header {
+		// keep what this request already set, such as its own request id
+		if _, ok := header[key]; !ok {
+			header[key] = values
+		}
+	}
+	header.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(response.Status)
+	_, _ = w.Write(response.Body)
+}
+
+// recordingWriter passes a response through while keeping a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if rw.status == 0 && status >= http.StatusOK {
+		rw.status = status
+		rw.header = rw.Header().Clone()
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recordingWriter) response() *idempotency.Response {
+	return &idempotency.Response{
+		Status: rw.status,
+		Header: rw.header,
+		Body:   rw.body.Bytes(),
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
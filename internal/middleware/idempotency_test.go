@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/giortzisg/go-boilerplate/pkg/idempotency"
+)
+
+func TestIdempotency(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	calls := 0
+	status := http.StatusCreated
+	handler := Idempotency(slog.New(slog.NewTextHandler(io.Discard, nil)), IdempotencyConfig{Store: store})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write(body)
+		}),
+	)
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/users/", strings.NewReader(body))
+		r.Header.Set(idempotency.Header, key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	first := send("key-1", `{"name":"Test User"}`)
+	if first.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("first request: status %d after %d calls", first.Code, calls)
+	}
+
+	retry := send("key-1", `{"name":"Test User"}`)
+	if retry.Code != http.StatusCreated || calls != 1 {
+		t.Errorf("retry: status %d after %d calls, want replay", retry.Code, calls)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != `{"name":"Test User"}` {
+		t.Errorf("retry: headers %v body %q", retry.Header(), retry.Body.String())
+	}
+	if retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("retry: Content-Type = %q", retry.Header().Get("Content-Type"))
+	}
+
+	if w := send("key-1", `{"name":"Other User"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key: status %d, want 422", w.Code)
+	}
+
+	status = http.StatusInternalServerError
+	if w := send("key-2", `{}`); w.Code != http.StatusInternalServerError || calls != 2 {
+		t.Fatalf("failing request: status %d after %d calls", w.Code, calls)
+	}
+	status = http.StatusCreated
+	if w := send("key-2", `{}`); w.Code != http.StatusCreated || calls != 3 {
+		t.Errorf("retry after server error: status %d after %d calls, want it to run again", w.Code, calls)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/users/", nil)
+	r.Header.Set(idempotency.Header, "key-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if calls != 4 {
+		t.Errorf("safe method: %d calls, want the handler to run", calls)
+	}
+}
+
+func TestIdempotency_BodyHeaders(t *testing.T) {
+	calls := 0
+	handler := Idempotency(slog.New(slog.NewTextHandler(io.Discard, nil)), IdempotencyConfig{Store: idempotency.NewMemoryStore()})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+
+	send := func(contentType, contentEncoding string) int {
+		r := httptest.NewRequest(http.MethodPost, "/users/", strings.NewReader(`{"name":"Test User"}`))
+		r.Header.Set(idempotency.Header, "key-1")
+		r.Header.Set("Content-Type", contentType)
+		if contentEncoding != "" {
+			r.Header.Set("Content-Encoding", contentEncoding)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := send("application/json", ""); code != http.StatusCreated || calls != 1 {
+		t.Fatalf("first request: status %d after %d calls", code, calls)
+	}
+	if code := send("application/json", ""); code != http.StatusCreated || calls != 1 {
+		t.Errorf("retry: status %d after %d calls, want replay", code, calls)
+	}
+	if code := send("application/msgpack", ""); code != http.StatusUnprocessableEntity {
+		t.Errorf("other Content-Type: status %d, want 422", code)
+	}
+	if code := send("application/json", "gzip"); code != http.StatusUnprocessableEntity {
+		t.Errorf("other Content-Encoding: status %d, want 422", code)
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want once", calls)
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	var retry *httptest.ResponseRecorder
+	var handler http.Handler
+	handler = Idempotency(slog.New(slog.NewTextHandler(io.Discard, nil)), IdempotencyConfig{Store: store})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// a retry arriving while the first request is still running
+			if retry == nil {
+				retry = httptest.NewRecorder()
+				rr := httptest.NewRequest(http.MethodPost, "/users/", strings.NewReader(`{}`))
+				rr.Header.Set(idempotency.Header, "key")
+				handler.ServeHTTP(retry, rr)
+			}
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+
+	r := httptest.NewRequest(http.MethodPost, "/users/", strings.NewReader(`{}`))
+	r.Header.Set(idempotency.Header, "key")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("first request: status %d", w.Code)
+	}
+	if retry.Code != http.StatusConflict || retry.Header().Get("Retry-After") == "" {
+		t.Errorf("concurrent retry: status %d, Retry-After %q", retry.Code, retry.Header().Get("Retry-After"))
+	}
+}
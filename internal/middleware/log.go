@@ -1,22 +1,146 @@
 package middleware
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
-func Logging(logger *slog.Logger) func(next http.Handler) http.Handler {
+type loggingOptions struct {
+	trustedProxies TrustedProxies
+	sampleRate     float64
+	level          func(status int) slog.Level
+	combined       io.Writer
+	combinedMu     sync.Mutex
+}
+
+type LoggingOption func(o *loggingOptions)
+
+// WithTrustedProxies makes the logged remote_ip honor X-Forwarded-For when
+// the request came through one of proxies.
+func WithTrustedProxies(proxies TrustedProxies) LoggingOption {
+	return func(o *loggingOptions) {
+		o.trustedProxies = proxies
+	}
+}
+
+// WithSampleRate logs only the given fraction, between 0 and 1, of requests
+// that did not fail. Responses with a 4xx or 5xx status are always logged.
+func WithSampleRate(rate float64) LoggingOption {
+	return func(o *loggingOptions) {
+		o.sampleRate = rate
+	}
+}
+
+// WithStatusLevel overrides how a response status maps to a log level.
+func WithStatusLevel(level func(status int) slog.Level) LoggingOption {
+	return func(o *loggingOptions) {
+		o.level = level
+	}
+}
+
+// WithCombinedLogFormat writes each access log entry to out in the
+// Apache/NCSA combined log format instead of through the slog logger.
+func WithCombinedLogFormat(out io.Writer) LoggingOption {
+	return func(o *loggingOptions) {
+		o.combined = out
+	}
+}
+
+// DefaultStatusLevel logs server errors at error, client errors at warn and
+// everything else at info.
+func DefaultStatusLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+func Logging(logger *slog.Logger, opts ...LoggingOption) func(next http.Handler) http.Handler {
+	o := &loggingOptions{
+		sampleRate: 1,
+		level:      DefaultStatusLevel,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			next.ServeHTTP(w, r)
-			logger.Info(
+			rw := wrapResponseWriter(w)
+
+			next.ServeHTTP(rw, r)
+
+			status := rw.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status < http.StatusBadRequest && o.sampleRate < 1 && rand.Float64() >= o.sampleRate {
+				return
+			}
+
+			remoteIP := o.trustedProxies.ClientIP(r)
+			if o.combined != nil {
+				o.writeCombined(r, start, status, rw.size, remoteIP)
+				return
+			}
+
+			var route string
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+
+			logger.LogAttrs(
+				r.Context(),
+				o.level(status),
 				"Request",
-				"method", r.Method,
-				"path", r.URL.Path,
-				"duration", time.Since(start),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int64("size", rw.size),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_ip", remoteIP),
+				slog.String("user_agent", r.UserAgent()),
 			)
 		})
 	}
 }
+
+// writeCombined emits %h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i".
+// Requests are not authenticated, so %u is always "-".
+func (o *loggingOptions) writeCombined(r *http.Request, start time.Time, status int, size int64, remoteIP string) {
+	bytesSent := "-"
+	if size > 0 {
+		bytesSent = strconv.FormatInt(size, 10)
+	}
+	referer := r.Referer()
+	if referer == "" {
+		referer = "-"
+	}
+
+	line := fmt.Sprintf("%s - - [%s] %q %d %s %q %q\n",
+		remoteIP,
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+r.URL.RequestURI()+" "+r.Proto,
+		status,
+		bytesSent,
+		referer,
+		r.UserAgent(),
+	)
+
+	o.combinedMu.Lock()
+	defer o.combinedMu.Unlock()
+	_, _ = io.WriteString(o.combined, line)
+}
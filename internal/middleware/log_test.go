@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTrustedProxies_ClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("failed to parse trusted proxies: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:1234", want: "203.0.113.7"},
+		{name: "untrusted peer is not believed", remoteAddr: "203.0.113.7:1234", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "trusted peer", remoteAddr: "10.0.0.2:1234", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.2:1234", forwarded: "198.51.100.1, 192.168.1.1, 10.1.1.1", want: "198.51.100.1"},
+		{name: "spoofed left-most entry is ignored", remoteAddr: "10.0.0.2:1234", forwarded: "1.2.3.4, 198.51.100.1", want: "198.51.100.1"},
+		{name: "only proxies", remoteAddr: "10.0.0.2:1234", forwarded: "10.0.0.3", want: "10.0.0.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := proxies.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	mux := chi.NewRouter()
+	mux.Use(Logging(logger))
+	mux.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("unavailable"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	r.Header.Set("User-Agent", "test-agent")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log entry: %v", err)
+	}
+	want := map[string]any{
+		"level":      "ERROR",
+		"route":      "/users/{id}",
+		"status":     float64(http.StatusServiceUnavailable),
+		"size":       float64(len("unavailable")),
+		"remote_ip":  "192.0.2.1",
+		"user_agent": "test-agent",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, entry[key])
+		}
+	}
+}
+
+func TestLogging_CombinedFormat(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+
+	handler := Logging(logger, WithCombinedLogFormat(&out))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/users?email=a", nil)
+	r.Header.Set("User-Agent", "test-agent")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	pattern := `^192\.0\.2\.1 - - \[[^\]]+\] "GET /users\?email=a HTTP/1\.1" 200 2 "-" "test-agent"\n$`
+	if !regexp.MustCompile(pattern).MatchString(out.String()) {
+		t.Errorf("unexpected combined log line %q", out.String())
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled, by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests, by method, route pattern and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	_ = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "http_recovered_panics_total",
+		Help: "Number of handler panics turned into 500 responses.",
+	}, func() float64 {
+		return float64(RecoveredPanics())
+	})
+)
+
+// Metrics records the count and latency of requests in the default
+// Prometheus registry. Routes are labelled with their chi pattern, and
+// requests that matched no route share a single label, to keep the label
+// cardinality independent of the paths clients send.
+func Metrics() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := wrapResponseWriter(w)
+
+			next.ServeHTTP(rw, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := rw.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			labels := prometheus.Labels{
+				"method": r.Method,
+				"route":  route,
+				"status": strconv.Itoa(status),
+			}
+			httpRequests.With(labels).Inc()
+			httpDuration.With(labels).Observe(time.Since(start).Seconds())
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	mux := chi.NewRouter()
+	mux.Use(Metrics())
+	mux.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	routeCounter := httpRequests.WithLabelValues(http.MethodGet, "/users/{id}", "204")
+	unmatchedCounter := httpRequests.WithLabelValues(http.MethodGet, "unmatched", "404")
+	beforeRoute, beforeUnmatched := testutil.ToFloat64(routeCounter), testutil.ToFloat64(unmatchedCounter)
+
+	for _, path := range []string{"/users/1", "/users/2", "/unknown"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(routeCounter) - beforeRoute; got != 2 {
+		t.Errorf("expected 2 requests for the route pattern, got %v", got)
+	}
+	if got := testutil.ToFloat64(unmatchedCounter) - beforeUnmatched; got != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", got)
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/giortzisg/go-boilerplate/internal/handlers"
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+)
+
+// Negotiate picks the response codec from the Accept header before the
+// handler runs, so that an unacceptable request is answered with 406 before
+// anything is created or changed. The codec is stored in the request
+// context where json.Encoder finds it.
+func Negotiate() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			codec, err := json.NegotiateRequest(r)
+			if err != nil {
+				handlers.WriteError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(json.NewContext(r.Context(), codec)))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "github.com/giortzisg/go-boilerplate/api/v1"
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     string
+		wantStatus int
+		wantType   string
+		wantCalled bool
+	}{
+		{name: "acceptable", accept: json.MsgPackContentType, wantStatus: http.StatusCreated, wantType: json.MsgPackContentType, wantCalled: true},
+		{name: "not acceptable", accept: "text/html", wantStatus: http.StatusNotAcceptable, wantType: json.JSONContentType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := Negotiate()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				_ = json.Encoder(w, r, &v1.Response{Code: http.StatusCreated}, http.StatusCreated)
+			}))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/users", nil)
+			r.Header.Set("Accept", tt.accept)
+			handler.ServeHTTP(w, r)
+
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %s, want %s", got, tt.wantType)
+			}
+			if got := w.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept" {
+				t.Errorf("Vary = %v, want [Accept]", got)
+			}
+		})
+	}
+}
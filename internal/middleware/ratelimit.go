@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/handlers"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+	"github.com/giortzisg/go-boilerplate/pkg/ratelimit"
+	"github.com/go-chi/chi/v5"
+)
+
+var ErrRateLimited = e.NewStatusError(errors.New("rate limit exceeded"), http.StatusTooManyRequests)
+
+// KeyFunc identifies the client a request is counted against.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP counts requests per client address.
+func KeyByIP(proxies TrustedProxies) KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + proxies.ClientIP(r)
+	}
+}
+
+// KeyByAPIKey counts requests per API key sent in header, falling back to
+// the client address. Keys are hashed so stores never hold the secret.
+func KeyByAPIKey(header string, proxies TrustedProxies) KeyFunc {
+	return func(r *http.Request) string {
+		if key := r.Header.Get(header); key != "" {
+			sum := sha256.Sum256([]byte(key))
+			return "key:" + hex.EncodeToString(sum[:16])
+		}
+		return "ip:" + proxies.ClientIP(r)
+	}
+}
+
+// RateLimit enforces limit per client and route with a token bucket kept
+// in store. Every response carries RateLimit-* headers, rejected requests
+// get a 429 with Retry-After. Store failures let the request through.
+func RateLimit(logger *slog.Logger, store ratelimit.Store, limit ratelimit.Limit, key KeyFunc) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			result, err := store.Take(r.Context(), r.Method+" "+route+" "+key(r), limit)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "rate limit store failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Policy", limit.Policy())
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+			if !result.Allowed {
+				header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+				handlers.WriteError(w, r, ErrRateLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
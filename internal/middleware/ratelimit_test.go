@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giortzisg/go-boilerplate/pkg/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))
+	handler := RateLimit(logger, ratelimit.NewMemoryStore(), ratelimit.PerMinute(2), KeyByIP(nil))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/users", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := request("203.0.113.7:1000")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected request %d to pass, got %d", i+1, w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("expected RateLimit-Remaining %s, got %s", wantRemaining, got)
+		}
+	}
+
+	w := request("203.0.113.7:1001")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, got %s", got)
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+		t.Errorf("expected RateLimit-Policy 2;w=60, got %s", got)
+	}
+
+	if w := request("198.51.100.1:1000"); w.Code != http.StatusOK {
+		t.Errorf("expected other clients to be unaffected, got %d", w.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/giortzisg/go-boilerplate/internal/handlers"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+)
+
+var (
+	ErrInternal = e.NewStatusError(errors.New("internal server error"), http.StatusInternalServerError)
+
+	recoveredPanics = expvar.NewInt("http_recovered_panics")
+)
+
+// RecoveredPanics reports how many handler panics Recover has caught.
+func RecoveredPanics() int64 {
+	return recoveredPanics.Value()
+}
+
+// Recover turns a panicking handler into a 500 response in the standard
+// error body and logs the panic value and stack. http.ErrAbortHandler is
+// re-raised so net/http can abort the response as intended.
+func Recover(logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := wrapResponseWriter(w)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				recoveredPanics.Add(1)
+				logger.ErrorContext(
+					r.Context(),
+					"Recovered from panic",
+					"panic", fmt.Sprint(rec),
+					"stack", string(debug.Stack()),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"remote_addr", r.RemoteAddr,
+					"user_agent", r.UserAgent(),
+				)
+
+				// a partially written response cannot be replaced anymore
+				if !rw.wroteHeader {
+					handlers.WriteError(rw, r, ErrInternal)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/giortzisg/go-boilerplate/pkg/correlation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestID propagates the X-Request-ID and W3C traceparent headers. Valid
+// inbound values are reused, a missing or malformed request id is replaced
+// by a generated one, and the trace is continued with a new span id. Both
+// are stored in the request context and echoed on the response. When the
+// server already started a span for the request, the trace is left to it.
+func RequestID() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(correlation.RequestIDHeader)
+			if !correlation.ValidRequestID(id) {
+				id = correlation.NewRequestID()
+			}
+
+			ctx := correlation.WithRequestID(r.Context(), id)
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				t := correlation.NewTraceParent()
+				if parent, ok := correlation.ParseTraceParent(r.Header.Get(correlation.TraceParentHeader)); ok {
+					t = parent.Child()
+				}
+				ctx = correlation.WithTraceParent(ctx, t)
+				w.Header().Set(correlation.TraceParentHeader, t.String())
+			}
+
+			w.Header().Set(correlation.RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/handlers"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+)
+
+var ErrServiceUnavailable = e.NewStatusError(errors.New("request could not be completed in time"), http.StatusServiceUnavailable)
+
+// Timeout cancels the request context once d has passed, so database calls
+// and anything else bound to it give up. Handlers run on the request's own
+// goroutine and are expected to return promptly once the context is done;
+// if one returns without having written anything, a 503 is sent for it.
+func Timeout(d time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			rw := wrapResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			if !rw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				handlers.WriteError(rw, r, ErrServiceUnavailable)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	mux := chi.NewRouter()
+	mux.Use(Tracing())
+	mux.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, path := range []string{"/users/42", "/unknown"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		ctx, span := tp.Tracer("test").Start(r.Context(), r.Method, trace.WithSpanKind(trace.SpanKindServer))
+		mux.ServeHTTP(httptest.NewRecorder(), r.WithContext(ctx))
+		span.End()
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "GET /users/{id}" {
+		t.Errorf("expected span to be named after the route, got %q", spans[0].Name)
+	}
+	if spans[1].Name != "GET" {
+		t.Errorf("expected unmatched request to keep its name, got %q", spans[1].Name)
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditRecord is one entry of the audit trail. Records are only ever
+// inserted, never updated or deleted. Changes holds the JSON encoded
+// audit.Diff of the target.
+type AuditRecord struct {
+	Id         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ActorIP    string    `gorm:"size:64;index"`
+	Action     string    `gorm:"size:64;not null;index"`
+	TargetType string    `gorm:"size:64;not null;index:idx_audit_target"`
+	TargetID   string    `gorm:"size:255;not null;index:idx_audit_target"`
+	Changes    string    `gorm:"type:text"`
+	RequestID  string    `gorm:"size:128;index"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+}
+
+func (r *AuditRecord) TableName() string {
+	return "audit_log"
+}
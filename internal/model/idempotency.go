@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key. Status stays 0 while the request is in flight, and
+// ExpiresAt is the end of its lease until the response is stored.
+type IdempotencyRecord struct {
+	IdempotencyKey string `gorm:"primaryKey;size:64"`
+	Fingerprint    string `gorm:"size:64;not null"`
+	Status         int    `gorm:"not null;default:0"`
+	Header         string `gorm:"type:text"`
+	Body           []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time `gorm:"index;not null"`
+}
+
+func (r *IdempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// LoginAttempt counts recent failed logins for one subject, either an
+// account or a client address.
+type LoginAttempt struct {
+	SubjectKey    string `gorm:"primaryKey;size:255"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+func (a *LoginAttempt) TableName() string {
+	return "login_attempts"
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of an OutboxEvent. Delivered events are removed.
+const (
+	OutboxPending = "pending"
+	OutboxDead    = "dead"
+)
+
+// OutboxEvent is a domain event waiting to be delivered. It is written in
+// the transaction of the change it describes. AvailableAt is when it is
+// next due, which a claim pushes back by its lease.
+type OutboxEvent struct {
+	Id          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Type        string    `gorm:"size:128;not null"`
+	AggregateID string    `gorm:"size:255;not null"`
+	Payload     string    `gorm:"type:text;not null"`
+	Status      string    `gorm:"size:16;not null;default:pending;index:idx_outbox_due"`
+	Attempts    int       `gorm:"not null;default:0"`
+	AvailableAt time.Time `gorm:"not null;index:idx_outbox_due"`
+	LastError   string    `gorm:"type:text"`
+	CreatedAt   time.Time `gorm:"not null"`
+}
+
+func (e *OutboxEvent) TableName() string {
+	return "outbox_events"
+}
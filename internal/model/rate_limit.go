@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type RateLimitBucket struct {
+	BucketKey string  `gorm:"primaryKey;size:255"`
+	Tokens    float64 `gorm:"not null"`
+	UpdatedAt time.Time
+	// RefilledAt is when the bucket is full again, after which the row
+	// can be deleted.
+	RefilledAt time.Time `gorm:"index"`
+}
+
+func (b *RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
@@ -11,6 +11,7 @@ type User struct {
 	Name      string    `gorm:"not null"`
 	Password  string    `gorm:"not null"`
 	Email     string    `gorm:"uniqueIndex;not null"`
+	Version   uint      `gorm:"not null;default:1"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a WebhookDelivery.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint registered to receive events. EventTypes is a
+// comma separated list of event types, where "*" stands for all of them.
+type Webhook struct {
+	Id         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	URL        string    `gorm:"size:2048;not null"`
+	Secret     string    `gorm:"size:255;not null"`
+	EventTypes string    `gorm:"size:1024;not null"`
+	Active     bool      `gorm:"not null;default:true"`
+	CreatedAt  time.Time `gorm:"not null"`
+	UpdatedAt  time.Time `gorm:"not null"`
+}
+
+func (w *Webhook) TableName() string {
+	return "webhooks"
+}
+
+// WebhookDelivery is an event on its way to a webhook. Payload is the body
+// that is signed and posted. It is kept once finished as the delivery
+// history. AvailableAt is when it is next due.
+type WebhookDelivery struct {
+	Id             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	WebhookID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event;index:idx_webhook_delivery_history"`
+	EventID        string    `gorm:"size:255;not null;uniqueIndex:idx_webhook_delivery_event"`
+	EventType      string    `gorm:"size:128;not null"`
+	Payload        string    `gorm:"type:text;not null"`
+	Status         string    `gorm:"size:16;not null;default:pending;index:idx_webhook_delivery_due"`
+	Attempts       int       `gorm:"not null;default:0"`
+	AvailableAt    time.Time `gorm:"not null;index:idx_webhook_delivery_due"`
+	LastStatusCode int
+	LastError      string    `gorm:"type:text"`
+	CreatedAt      time.Time `gorm:"not null;index:idx_webhook_delivery_history"`
+	UpdatedAt      time.Time `gorm:"not null"`
+}
+
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookAttempt records one request of a delivery and what the endpoint
+// answered. StatusCode is zero when no response was received.
+type WebhookAttempt struct {
+	Id           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	DeliveryID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	Attempt      int       `gorm:"not null"`
+	StatusCode   int
+	Error        string    `gorm:"type:text"`
+	ResponseBody string    `gorm:"type:text"`
+	DurationMs   int64     `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"not null"`
+}
+
+func (a *WebhookAttempt) TableName() string {
+	return "webhook_attempts"
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/model"
+)
+
+// AuditFilter selects audit records. Zero fields match everything; From is
+// inclusive and To exclusive.
+type AuditFilter struct {
+	ActorIP    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditRepository stores the audit trail. It has no way to change or
+// remove records on purpose.
+type AuditRepository interface {
+	Create(ctx context.Context, record *model.AuditRecord) error
+	// List returns a page of the matching records, newest first, and the
+	// number of matching records in total.
+	List(ctx context.Context, filter AuditFilter) ([]model.AuditRecord, int64, error)
+}
+
+func NewAuditRepository(
+	r *Repository,
+) AuditRepository {
+	return &auditRepository{
+		Repository: r,
+	}
+}
+
+type auditRepository struct {
+	*Repository
+}
+
+func (r *auditRepository) Create(ctx context.Context, record *model.AuditRecord) error {
+	return r.DB(ctx).Create(record).Error
+}
+
+func (r *auditRepository) List(ctx context.Context, filter AuditFilter) ([]model.AuditRecord, int64, error) {
+	query := r.DB(ctx).Model(&model.AuditRecord{})
+	if filter.ActorIP != "" {
+		query = query.Where("actor_ip = ?", filter.ActorIP)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []model.AuditRecord
+	if err := query.Order("created_at DESC").Order("id").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupAuditRepository(t *testing.T) (AuditRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+	return NewAuditRepository(NewRepository(slog.New(slog.NewJSONHandler(os.Stdout, nil)), db)), mock
+}
+
+func TestAuditRepository_Create(t *testing.T) {
+	repo, mock := setupAuditRepository(t)
+	record := &model.AuditRecord{
+		Id:         uuid.New(),
+		ActorIP:    "192.0.2.1",
+		Action:     "user.update",
+		TargetType: "user",
+		TargetID:   "user-1",
+		Changes:    `{}`,
+		RequestID:  "req-1",
+		CreatedAt:  time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "audit_log" ("id","actor_ip","action","target_type","target_id","changes","request_id","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`)).
+		WithArgs(record.Id, "192.0.2.1", "user.update", "user", "user-1", `{}`, "req-1", record.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Create(context.Background(), record))
+}
+
+func TestAuditRepository_List(t *testing.T) {
+	repo, mock := setupAuditRepository(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "audit_log" WHERE actor_ip = $1 AND target_type = $2 AND created_at >= $3`)).
+		WithArgs("192.0.2.1", "user", from).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "audit_log" WHERE actor_ip = $1 AND target_type = $2 AND created_at >= $3 ORDER BY created_at DESC,id LIMIT $4 OFFSET $5`)).
+		WithArgs("192.0.2.1", "user", from, 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "action"}).AddRow(id, "user.create"))
+
+	records, total, err := repo.List(context.Background(), AuditFilter{
+		ActorIP:    "192.0.2.1",
+		TargetType: "user",
+		From:       from,
+		Limit:      10,
+		Offset:     20,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), total)
+	assert.Len(t, records, 1)
+	assert.Equal(t, id, records[0].Id)
+}
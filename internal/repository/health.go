@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// NewDBHealthCheck pings the connection pool of db.
+func NewDBHealthCheck(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// NewDBCloser closes the connection pool of db, for use as a shutdown hook.
+func NewDBCloser(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/pkg/idempotency"
+	"gorm.io/gorm/clause"
+)
+
+const idempotencyPurgeInterval = time.Minute
+
+// NewIdempotencyStore keeps idempotency records in the database so retries
+// are recognized whichever replica they reach.
+func NewIdempotencyStore(r *Repository) idempotency.Store {
+	return &idempotencyRepository{
+		Repository: r,
+		now:        time.Now,
+	}
+}
+
+type idempotencyRepository struct {
+	*Repository
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// Begin inserts the record unless the key is already held. Expired rows are
+// deleted first, so their keys can be claimed again, whether they hold a
+// response past its TTL or an in-flight claim past its lease.
+func (r *idempotencyRepository) Begin(ctx context.Context, key, fingerprint string, lease time.Duration) (*idempotency.Record, bool, error) {
+	now := r.now()
+	if err := r.purge(ctx, now); err != nil {
+		return nil, false, err
+	}
+	if err := r.DB(ctx).
+		Where("idempotency_key = ? AND expires_at <= ?", key, now).
+		Delete(&model.IdempotencyRecord{}).Error; err != nil {
+		return nil, false, err
+	}
+
+	result := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.IdempotencyRecord{
+		IdempotencyKey: key,
+		Fingerprint:    fingerprint,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(lease),
+	})
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return &idempotency.Record{Fingerprint: fingerprint}, true, nil
+	}
+
+	var row model.IdempotencyRecord
+	if err := r.DB(ctx).Where("idempotency_key = ?", key).First(&row).Error; err != nil {
+		return nil, false, err
+	}
+
+	record := &idempotency.Record{Fingerprint: row.Fingerprint}
+	if row.Status != 0 {
+		record.Response = &idempotency.Response{Status: row.Status, Body: row.Body}
+		if err := json.Unmarshal([]byte(row.Header), &record.Response.Header); err != nil {
+			return nil, false, err
+		}
+	}
+	return record, false, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, key string, response *idempotency.Response, ttl time.Duration) error {
+	header := response.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	return r.DB(ctx).Model(&model.IdempotencyRecord{}).
+		Where("idempotency_key = ?", key).
+		Updates(map[string]interface{}{
+			"status":     response.Status,
+			"header":     string(encoded),
+			"body":       response.Body,
+			"expires_at": r.now().Add(ttl),
+		}).Error
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key string) error {
+	return r.DB(ctx).Where("idempotency_key = ?", key).Delete(&model.IdempotencyRecord{}).Error
+}
+
+// purge deletes every expired record, at most once per interval.
+func (r *idempotencyRepository) purge(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	if now.Sub(r.lastPurge) < idempotencyPurgeInterval {
+		r.mu.Unlock()
+		return nil
+	}
+	r.lastPurge = now
+	r.mu.Unlock()
+
+	return r.DB(ctx).Where("expires_at <= ?", now).Delete(&model.IdempotencyRecord{}).Error
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/giortzisg/go-boilerplate/pkg/idempotency"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestIdempotencyRepository_Begin(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	}()
+
+	now := time.Now()
+	store := &idempotencyRepository{
+		Repository: NewRepository(slog.New(slog.NewJSONHandler(os.Stdout, nil)), db),
+		now:        func() time.Time { return now },
+		lastPurge:  now,
+	}
+
+	// a fresh key is claimed
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "idempotency_keys" WHERE idempotency_key = $1 AND expires_at <= $2`)).
+		WithArgs("fresh", now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "idempotency_keys" ("idempotency_key","fingerprint","status","header","body","created_at","expires_at") VALUES ($1,$2,$3,$4,$5,$6,$7) ON CONFLICT DO NOTHING`)).
+		WithArgs("fresh", "fingerprint", 0, "", sqlmock.AnyArg(), now, now.Add(time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	record, claimed, err := store.Begin(context.Background(), "fresh", "fingerprint", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	assert.True(t, record.InFlight())
+
+	// a completed key returns the stored response
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "idempotency_keys" WHERE idempotency_key = $1 AND expires_at <= $2`)).
+		WithArgs("done", now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "idempotency_keys"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "idempotency_keys" WHERE idempotency_key = $1 ORDER BY "idempotency_keys"."idempotency_key" LIMIT $2`)).
+		WithArgs("done", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"idempotency_key", "fingerprint", "status", "header", "body", "created_at", "expires_at"}).
+			AddRow("done", "fingerprint", http.StatusCreated, `{"Content-Type":["application/json"]}`, []byte(`{}`), now, now.Add(time.Hour)))
+
+	record, claimed, err = store.Begin(context.Background(), "done", "fingerprint", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, &idempotency.Response{
+		Status: http.StatusCreated,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   []byte(`{}`),
+	}, record.Response)
+
+	// an in-flight claim past its lease is reclaimed
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "idempotency_keys" WHERE idempotency_key = $1 AND expires_at <= $2`)).
+		WithArgs("abandoned", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "idempotency_keys"`)).
+		WithArgs("abandoned", "fingerprint", 0, "", sqlmock.AnyArg(), now, now.Add(time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, claimed, err = store.Begin(context.Background(), "abandoned", "fingerprint", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestIdempotencyRepository_Complete(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	}()
+
+	now := time.Now()
+	store := &idempotencyRepository{
+		Repository: NewRepository(slog.New(slog.NewJSONHandler(os.Stdout, nil)), db),
+		now:        func() time.Time { return now },
+		lastPurge:  now,
+	}
+
+	// the stored response is kept for the TTL rather than the lease
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "idempotency_keys" SET "body"=$1,"expires_at"=$2,"header"=$3,"status"=$4 WHERE idempotency_key = $5`)).
+		WithArgs([]byte(`{}`), now.Add(24*time.Hour), `{"Content-Type":["application/json"]}`, http.StatusCreated, "done").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = store.Complete(context.Background(), "done", &idempotency.Response{
+		Status: http.StatusCreated,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   []byte(`{}`),
+	}, 24*time.Hour)
+	assert.NoError(t, err)
+}
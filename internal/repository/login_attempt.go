@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"gorm.io/gorm/clause"
+)
+
+const loginAttemptPurgeInterval = time.Minute
+
+type LoginAttemptRepository interface {
+	// Acquire returns the counters of key, creating them when missing, and
+	// locks them until the surrounding transaction ends, so that the logins
+	// of one subject are checked and counted one at a time.
+	Acquire(ctx context.Context, key string) (*model.LoginAttempt, error)
+	// Save writes back counters returned by Acquire.
+	Save(ctx context.Context, attempt *model.LoginAttempt) error
+	Reset(ctx context.Context, key string) error
+	// Purge deletes the counters whose last failure and lock both ended
+	// before the given time, which behave like missing ones. It runs at
+	// most once per interval, so it can be called on every login.
+	Purge(ctx context.Context, before time.Time) error
+}
+
+func NewLoginAttemptRepository(
+	r *Repository,
+) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		Repository: r,
+		now:        time.Now,
+	}
+}
+
+type loginAttemptRepository struct {
+	*Repository
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+func (r *loginAttemptRepository) Acquire(ctx context.Context, key string) (*model.LoginAttempt, error) {
+	if err := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.LoginAttempt{
+		SubjectKey: key,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	var attempt model.LoginAttempt
+	if err := r.DB(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("subject_key = ?", key).
+		First(&attempt).Error; err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepository) Save(ctx context.Context, attempt *model.LoginAttempt) error {
+	return r.DB(ctx).Model(attempt).Updates(map[string]interface{}{
+		"failures":        attempt.Failures,
+		"last_failure_at": attempt.LastFailureAt,
+		"locked_until":    attempt.LockedUntil,
+	}).Error
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	return r.DB(ctx).Where("subject_key = ?", key).Delete(&model.LoginAttempt{}).Error
+}
+
+func (r *loginAttemptRepository) Purge(ctx context.Context, before time.Time) error {
+	now := r.now()
+	r.mu.Lock()
+	if now.Sub(r.lastPurge) < loginAttemptPurgeInterval {
+		r.mu.Unlock()
+		return nil
+	}
+	r.lastPurge = now
+	r.mu.Unlock()
+
+	return r.DB(ctx).
+		Where("last_failure_at <= ? AND (locked_until IS NULL OR locked_until <= ?)", before, before).
+		Delete(&model.LoginAttempt{}).Error
+}
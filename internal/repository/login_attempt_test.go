@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestLoginAttemptRepository_Purge(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	}()
+
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	repo := &loginAttemptRepository{
+		Repository: NewRepository(slog.New(slog.NewJSONHandler(os.Stdout, nil)), db),
+		now:        func() time.Time { return now },
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "login_attempts" WHERE last_failure_at <= $1 AND (locked_until IS NULL OR locked_until <= $2)`)).
+		WithArgs(before, before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Purge(context.Background(), before))
+	// a second purge within the interval does not hit the database
+	assert.NoError(t, repo.Purge(context.Background(), before))
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+const metricsStartKey = "metrics:start"
+
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_query_duration_seconds",
+	Help:    "Latency of SQL statements executed through GORM, by operation and table.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"operation", "table", "status"})
+
+// metricsPlugin times every GORM operation. It hooks the first and last
+// callback of each processor so the measurement spans the whole statement.
+type metricsPlugin struct{}
+
+func (metricsPlugin) Name() string {
+	return "metrics"
+}
+
+func (p metricsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	registrations := []error{
+		cb.Create().Before("*").Register("metrics:before_create", p.before),
+		cb.Create().After("*").Register("metrics:after_create", p.after("create")),
+		cb.Query().Before("*").Register("metrics:before_query", p.before),
+		cb.Query().After("*").Register("metrics:after_query", p.after("query")),
+		cb.Update().Before("*").Register("metrics:before_update", p.before),
+		cb.Update().After("*").Register("metrics:after_update", p.after("update")),
+		cb.Delete().Before("*").Register("metrics:before_delete", p.before),
+		cb.Delete().After("*").Register("metrics:after_delete", p.after("delete")),
+		cb.Row().Before("*").Register("metrics:before_row", p.before),
+		cb.Row().After("*").Register("metrics:after_row", p.after("row")),
+		cb.Raw().Before("*").Register("metrics:before_raw", p.before),
+		cb.Raw().After("*").Register("metrics:after_raw", p.after("raw")),
+	}
+	return errors.Join(registrations...)
+}
+
+func (metricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+func (metricsPlugin) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+
+		status := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+		queryDuration.WithLabelValues(operation, db.Statement.Table, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// registerDBStats exposes the connection pool statistics of sqlDB. Opening
+// the same database twice keeps the collector registered first.
+func registerDBStats(sqlDB *sql.DB, name string) error {
+	err := prometheus.Register(collectors.NewDBStatsCollector(sqlDB, name))
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		return nil
+	}
+	return err
+}
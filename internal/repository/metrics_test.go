@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// observed returns the number of statements recorded under the labels,
+// which is the query counter, and their total duration.
+func observed(t *testing.T, operation, table, status string) (uint64, float64) {
+	t.Helper()
+	var metric dto.Metric
+	if err := queryDuration.WithLabelValues(operation, table, status).(prometheus.Histogram).Write(&metric); err != nil {
+		t.Fatalf("failed to read histogram: %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+func TestMetricsPlugin(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	if err = db.Use(metricsPlugin{}); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+
+	queries, _ := observed(t, "query", "users", "ok")
+	failures, _ := observed(t, "create", "users", "error")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE email = $1`)).
+		WithArgs("test@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(uuid.New(), "test@example.com"))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "users"`)).WillReturnError(errors.New("duplicate key"))
+	mock.ExpectRollback()
+
+	var user model.User
+	if err = db.WithContext(context.Background()).Where("email = ?", "test@example.com").First(&user).Error; err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if err = db.Create(&model.User{Id: uuid.New()}).Error; err == nil {
+		t.Fatal("expected the insert to fail")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	if got, sum := observed(t, "query", "users", "ok"); got-queries != 1 || sum <= 0 {
+		t.Errorf("query/users/ok recorded %d statements taking %v, want 1 with a duration", got-queries, sum)
+	}
+	if got, _ := observed(t, "create", "users", "error"); got-failures != 1 {
+		t.Errorf("create/users/error recorded %d statements, want 1", got-failures)
+	}
+}
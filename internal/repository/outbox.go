@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/pkg/events"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository writes domain events to the outbox. Call Add inside
+// the transaction of the change the event describes.
+type OutboxRepository interface {
+	Add(ctx context.Context, event *model.OutboxEvent) error
+}
+
+func NewOutboxRepository(
+	r *Repository,
+) OutboxRepository {
+	return newOutboxRepository(r)
+}
+
+// NewOutboxStore reads the outbox for an events.Dispatcher. Claims skip
+// rows locked by other replicas, so several dispatchers can run at once.
+func NewOutboxStore(r *Repository) events.Store {
+	return newOutboxRepository(r)
+}
+
+func newOutboxRepository(r *Repository) *outboxRepository {
+	return &outboxRepository{
+		Repository: r,
+		now:        time.Now,
+	}
+}
+
+type outboxRepository struct {
+	*Repository
+	now func() time.Time
+}
+
+func (r *outboxRepository) Add(ctx context.Context, event *model.OutboxEvent) error {
+	return r.DB(ctx).Create(event).Error
+}
+
+func (r *outboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]events.Event, error) {
+	now := r.now()
+	var rows []model.OutboxEvent
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND available_at <= ?", model.OutboxPending, now).
+			Order("available_at").
+			Limit(limit).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		ids := make([]any, len(rows))
+		for i := range rows {
+			ids[i] = rows[i].Id
+			rows[i].Attempts++
+		}
+		return r.DB(ctx).Model(&model.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Updates(map[string]any{
+				"attempts":     gorm.Expr("attempts + 1"),
+				"available_at": now.Add(lease),
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]events.Event, len(rows))
+	for i, row := range rows {
+		claimed[i] = events.Event{
+			ID:          row.Id.String(),
+			Type:        row.Type,
+			AggregateID: row.AggregateID,
+			Payload:     []byte(row.Payload),
+			OccurredAt:  row.CreatedAt,
+			Attempts:    row.Attempts,
+		}
+	}
+	return claimed, nil
+}
+
+func (r *outboxRepository) Delivered(ctx context.Context, id string) error {
+	return r.DB(ctx).Where("id = ?", id).Delete(&model.OutboxEvent{}).Error
+}
+
+func (r *outboxRepository) Retry(ctx context.Context, id string, next time.Time, reason string) error {
+	return r.DB(ctx).Model(&model.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"available_at": next,
+			"last_error":   reason,
+		}).Error
+}
+
+func (r *outboxRepository) DeadLetter(ctx context.Context, id string, reason string) error {
+	return r.DB(ctx).Model(&model.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":     model.OutboxDead,
+			"last_error": reason,
+		}).Error
+}
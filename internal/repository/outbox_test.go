@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupOutboxRepository(t *testing.T, now time.Time) (*outboxRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+	repo := newOutboxRepository(NewRepository(slog.New(slog.NewJSONHandler(os.Stdout, nil)), db))
+	repo.now = func() time.Time { return now }
+	return repo, mock
+}
+
+func TestOutboxRepository_Claim(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo, mock := setupOutboxRepository(t, now)
+	id := uuid.New()
+	createdAt := now.Add(-time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbox_events" WHERE status = $1 AND available_at <= $2 ORDER BY available_at LIMIT $3 FOR UPDATE SKIP LOCKED`)).
+		WithArgs(model.OutboxPending, now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "aggregate_id", "payload", "status", "attempts", "available_at", "last_error", "created_at"}).
+			AddRow(id, "user.created", "user-1", `{"name":"Ann"}`, model.OutboxPending, 2, createdAt, "", createdAt))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_events" SET "attempts"=attempts + 1,"available_at"=$1 WHERE id IN ($2)`)).
+		WithArgs(now.Add(time.Minute), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	claimed, err := repo.Claim(context.Background(), 10, time.Minute)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, id.String(), claimed[0].ID)
+		assert.Equal(t, "user.created", claimed[0].Type)
+		assert.Equal(t, "user-1", claimed[0].AggregateID)
+		assert.Equal(t, `{"name":"Ann"}`, string(claimed[0].Payload))
+		assert.Equal(t, createdAt, claimed[0].OccurredAt)
+		assert.Equal(t, 3, claimed[0].Attempts)
+	}
+}
+
+func TestOutboxRepository_ClaimNothingDue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo, mock := setupOutboxRepository(t, now)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbox_events"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	claimed, err := repo.Claim(context.Background(), 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, claimed)
+}
+
+func TestOutboxRepository_Outcomes(t *testing.T) {
+	repo, mock := setupOutboxRepository(t, time.Now())
+	id := uuid.NewString()
+	next := time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "outbox_events" WHERE id = $1`)).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	assert.NoError(t, repo.Delivered(context.Background(), id))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_events" SET "available_at"=$1,"last_error"=$2 WHERE id = $3`)).
+		WithArgs(next, "timeout", id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	assert.NoError(t, repo.Retry(context.Background(), id, next, "timeout"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox_events" SET "last_error"=$1,"status"=$2 WHERE id = $3`)).
+		WithArgs("timeout", model.OutboxDead, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	assert.NoError(t, repo.DeadLetter(context.Background(), id, "timeout"))
+}
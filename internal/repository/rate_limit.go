@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/pkg/ratelimit"
+	"gorm.io/gorm/clause"
+)
+
+const rateLimitPurgeInterval = time.Minute
+
+// NewRateLimitStore keeps token buckets in the database so every replica
+// enforces the same limits.
+func NewRateLimitStore(r *Repository) ratelimit.Store {
+	return &rateLimitRepository{
+		Repository: r,
+		now:        time.Now,
+	}
+}
+
+type rateLimitRepository struct {
+	*Repository
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// Take makes sure the bucket row exists, then locks it for the refill and
+// update so concurrent requests for one key are serialized. Buckets that
+// have refilled are deleted first, since a missing bucket behaves exactly
+// like a full one.
+func (r *rateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	if err := r.purge(ctx, r.now()); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	var result ratelimit.Result
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		now := r.now()
+		full := limit.Full(now)
+		if err := r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.RateLimitBucket{
+			BucketKey:  key,
+			Tokens:     full.Tokens,
+			UpdatedAt:  full.UpdatedAt,
+			RefilledAt: full.UpdatedAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		var row model.RateLimitBucket
+		if err := r.DB(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("bucket_key = ?", key).
+			First(&row).Error; err != nil {
+			return err
+		}
+
+		var bucket ratelimit.Bucket
+		bucket, result = limit.Take(ratelimit.Bucket{Tokens: row.Tokens, UpdatedAt: row.UpdatedAt}, now)
+		return r.DB(ctx).Model(&row).Updates(map[string]interface{}{
+			"tokens":      bucket.Tokens,
+			"updated_at":  bucket.UpdatedAt,
+			"refilled_at": limit.RefilledAt(bucket),
+		}).Error
+	})
+	return result, err
+}
+
+// purge deletes every refilled bucket, at most once per interval.
+func (r *rateLimitRepository) purge(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	if now.Sub(r.lastPurge) < rateLimitPurgeInterval {
+		r.mu.Unlock()
+		return nil
+	}
+	r.lastPurge = now
+	r.mu.Unlock()
+
+	return r.DB(ctx).Where("refilled_at <= ?", now).Delete(&model.RateLimitBucket{}).Error
+}
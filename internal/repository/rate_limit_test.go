@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/giortzisg/go-boilerplate/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestRateLimitRepository_Take(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	}()
+
+	now := time.Now()
+	store := &rateLimitRepository{
+		Repository: NewRepository(slog.New(slog.NewJSONHandler(os.Stdout, nil)), db),
+		now:        func() time.Time { return now },
+		lastPurge:  now,
+	}
+	limit := ratelimit.PerMinute(60)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "rate_limit_buckets" ("bucket_key","tokens","updated_at","refilled_at") VALUES ($1,$2,$3,$4) ON CONFLICT DO NOTHING`)).
+		WithArgs("POST /users ip:203.0.113.7", float64(60), now, now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "rate_limit_buckets" WHERE bucket_key = $1 ORDER BY "rate_limit_buckets"."bucket_key" LIMIT $2 FOR UPDATE`)).
+		WithArgs("POST /users ip:203.0.113.7", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket_key", "tokens", "updated_at", "refilled_at"}).
+			AddRow("POST /users ip:203.0.113.7", 0.5, now.Add(-time.Second), now.Add(59*time.Second)))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "rate_limit_buckets" SET "refilled_at"=$1,"tokens"=$2,"updated_at"=$3 WHERE "bucket_key" = $4`)).
+		WithArgs(now.Add(59500*time.Millisecond), 0.5, now, "POST /users ip:203.0.113.7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := store.Take(context.Background(), "POST /users ip:203.0.113.7", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestRateLimitRepository_Purge(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	}()
+
+	now := time.Now()
+	store := &rateLimitRepository{
+		Repository: NewRepository(slog.New(slog.NewJSONHandler(os.Stdout, nil)), db),
+		now:        func() time.Time { return now },
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "rate_limit_buckets" WHERE refilled_at <= $1`)).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.purge(context.Background(), now))
+	// a second purge within the interval does not hit the database
+	assert.NoError(t, store.purge(context.Background(), now.Add(time.Second)))
+}
@@ -43,11 +43,13 @@ func NewTransaction(r *Repository) Transaction {
 
 // DB return tx
 // If you need to create a Transaction, you must call DB(ctx) and Transaction(ctx,fn)
+// Statements are bound to ctx, inside a transaction too, so they are
+// cancelled with it and honor its deadline.
 func (r *Repository) DB(ctx context.Context) *gorm.DB {
 	v := ctx.Value(ctxTxKey)
 	if v != nil {
 		if tx, ok := v.(*gorm.DB); ok {
-			return tx
+			return tx.WithContext(ctx)
 		}
 	}
 	return r.db.WithContext(ctx)
@@ -84,9 +86,13 @@ func NewDB(conf *viper.Viper, logger *slog.Logger) *gorm.DB {
 		db, err = gorm.Open(postgres.New(postgres.Config{
 			DSN:                  dsn,
 			PreferSimpleProtocol: true, // disables implicit prepared statement usage
-		}), &gorm.Config{})
+		}), &gorm.Config{
+			Logger: gormLogger,
+		})
 	case "sqlite":
-		db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+		db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{
+			Logger: gormLogger,
+		})
 	default:
 		panic("unknown db driver")
 	}
@@ -95,6 +101,13 @@ func NewDB(conf *viper.Viper, logger *slog.Logger) *gorm.DB {
 	}
 	db = db.Debug()
 
+	if err = db.Use(metricsPlugin{}); err != nil {
+		panic(err)
+	}
+	if err = db.Use(newTracingPlugin(driver)); err != nil {
+		panic(err)
+	}
+
 	// Connection Pool config
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -103,5 +116,9 @@ func NewDB(conf *viper.Viper, logger *slog.Logger) *gorm.DB {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	if err = registerDBStats(sqlDB, driver); err != nil {
+		panic(err)
+	}
 	return db
 }
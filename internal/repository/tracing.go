@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "tracing:span"
+
+// tracingPlugin starts a client span for every statement GORM executes, as
+// a child of the span found in the statement context that Repository.DB sets.
+type tracingPlugin struct {
+	tracer trace.Tracer
+	system string
+}
+
+func newTracingPlugin(system string) tracingPlugin {
+	return tracingPlugin{
+		tracer: otel.Tracer("github.com/giortzisg/go-boilerplate/internal/repository"),
+		system: system,
+	}
+}
+
+func (tracingPlugin) Name() string {
+	return "tracing"
+}
+
+func (p tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	registrations := []error{
+		cb.Create().Before("*").Register("tracing:before_create", p.before("create")),
+		cb.Create().After("*").Register("tracing:after_create", p.after),
+		cb.Query().Before("*").Register("tracing:before_query", p.before("query")),
+		cb.Query().After("*").Register("tracing:after_query", p.after),
+		cb.Update().Before("*").Register("tracing:before_update", p.before("update")),
+		cb.Update().After("*").Register("tracing:after_update", p.after),
+		cb.Delete().Before("*").Register("tracing:before_delete", p.before("delete")),
+		cb.Delete().After("*").Register("tracing:after_delete", p.after),
+		cb.Row().Before("*").Register("tracing:before_row", p.before("row")),
+		cb.Row().After("*").Register("tracing:after_row", p.after),
+		cb.Raw().Before("*").Register("tracing:before_raw", p.before("raw")),
+		cb.Raw().After("*").Register("tracing:after_raw", p.after),
+	}
+	return errors.Join(registrations...)
+}
+
+func (p tracingPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+		_, span := p.tracer.Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(p.system),
+				semconv.DBOperationName(operation),
+			),
+		)
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func (tracingPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBCollectionName(db.Statement.Table),
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
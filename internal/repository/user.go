@@ -2,13 +2,19 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"github.com/giortzisg/go-boilerplate/internal/model"
 	"github.com/google/uuid"
 )
 
+// ErrVersionConflict is returned by Update and Delete when the stored row no longer
+// carries the version the caller read, i.e. someone else updated it first.
+var ErrVersionConflict = errors.New("version conflict")
+
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	Update(ctx context.Context, user *model.User) error
+	Delete(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
 }
@@ -32,9 +38,35 @@ func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 	return nil
 }
 
+// Update writes user only if its Version still matches the stored row and
+// bumps the version on success. On ErrVersionConflict user is left untouched.
 func (r *userRepository) Update(ctx context.Context, user *model.User) error {
-	if err := r.DB(ctx).Save(user).Error; err != nil {
-		return err
+	version := user.Version
+	user.Version = version + 1
+
+	result := r.DB(ctx).Model(user).
+		Select("name", "password", "email", "version", "updated_at").
+		Where("version = ?", version).
+		Updates(user)
+	if result.Error != nil {
+		user.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		user.Version = version
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Delete soft deletes user if its Version still matches the stored row.
+func (r *userRepository) Delete(ctx context.Context, user *model.User) error {
+	result := r.DB(ctx).Where("version = ?", user.Version).Delete(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
 	}
 	return nil
 }
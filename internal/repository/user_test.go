@@ -55,6 +55,7 @@ func TestUserRepository_Create(t *testing.T) {
 		Name:      "Test User",
 		Email:     "test@example.com",
 		Password:  "password123",
+		Version:   1,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -62,7 +63,7 @@ func TestUserRepository_Create(t *testing.T) {
 	t.Run("successful creation", func(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "users"`)).
-			WithArgs(testUser.Id, testUser.Name, testUser.Password, testUser.Email, testUser.CreatedAt, testUser.UpdatedAt, nil).
+			WithArgs(testUser.Id, testUser.Name, testUser.Password, testUser.Email, testUser.Version, testUser.CreatedAt, testUser.UpdatedAt, nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
@@ -73,7 +74,7 @@ func TestUserRepository_Create(t *testing.T) {
 	t.Run("creation error", func(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "users"`)).
-			WithArgs(testUser.Id, testUser.Name, testUser.Password, testUser.Email, testUser.CreatedAt, testUser.UpdatedAt, nil).
+			WithArgs(testUser.Id, testUser.Name, testUser.Password, testUser.Email, testUser.Version, testUser.CreatedAt, testUser.UpdatedAt, nil).
 			WillReturnError(sql.ErrConnDone)
 		mock.ExpectRollback()
 
@@ -139,36 +140,57 @@ func TestUserRepository_Update(t *testing.T) {
 
 	ctx := context.Background()
 
-	testUser := &model.User{
-		Id:        uuid.New(),
-		Name:      "Updated User",
-		Email:     "test@example.com",
-		Password:  "password123",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	newTestUser := func() *model.User {
+		return &model.User{
+			Id:        uuid.New(),
+			Name:      "Updated User",
+			Email:     "test@example.com",
+			Password:  "password123",
+			Version:   3,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
 	}
+	updateQuery := regexp.QuoteMeta(`UPDATE "users" SET "name"=$1,"password"=$2,"email"=$3,"version"=$4,"updated_at"=$5 WHERE version = $6 AND "users"."deleted_at" IS NULL AND "id" = $7`)
 
 	t.Run("successful update", func(t *testing.T) {
+		testUser := newTestUser()
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "name"=$1,"password"=$2,"email"=$3,"created_at"=$4,"updated_at"=$5,"deleted_at"=$6 WHERE "users"."deleted_at" IS NULL AND "id" = $7`)).
-			WithArgs(testUser.Name, testUser.Password, testUser.Email, any.Time{}, any.Time{}, nil, testUser.Id).
+		mock.ExpectExec(updateQuery).
+			WithArgs(testUser.Name, testUser.Password, testUser.Email, uint(4), any.Time{}, uint(3), testUser.Id).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
 		err := userRepo.Update(ctx, testUser)
 		assert.NoError(t, err)
+		assert.Equal(t, uint(4), testUser.Version)
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		testUser := newTestUser()
+		mock.ExpectBegin()
+		mock.ExpectExec(updateQuery).
+			WithArgs(testUser.Name, testUser.Password, testUser.Email, uint(4), any.Time{}, uint(3), testUser.Id).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := userRepo.Update(ctx, testUser)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.Equal(t, uint(3), testUser.Version)
 	})
 
 	t.Run("update error", func(t *testing.T) {
+		testUser := newTestUser()
 		mock.ExpectBegin()
-		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "name"=$1,"password"=$2,"email"=$3,"created_at"=$4,"updated_at"=$5,"deleted_at"=$6 WHERE "users"."deleted_at" IS NULL AND "id" = $7`)).
-			WithArgs(testUser.Name, testUser.Password, testUser.Email, any.Time{}, any.Time{}, nil, testUser.Id).
+		mock.ExpectExec(updateQuery).
+			WithArgs(testUser.Name, testUser.Password, testUser.Email, uint(4), any.Time{}, uint(3), testUser.Id).
 			WillReturnError(sql.ErrConnDone)
 		mock.ExpectRollback()
 
 		err := userRepo.Update(ctx, testUser)
 		assert.Error(t, err)
 		assert.Equal(t, sql.ErrConnDone, err)
+		assert.Equal(t, uint(3), testUser.Version)
 	})
 }
 
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/giortzisg/go-boilerplate/pkg/events"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookDeliveryFilter selects the deliveries of a webhook. An empty
+// Status matches every status.
+type WebhookDeliveryFilter struct {
+	WebhookID uuid.UUID
+	Status    string
+	Limit     int
+	Offset    int
+}
+
+// WebhookRepository stores webhooks, their deliveries and the attempts
+// made for each delivery.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Webhook, error)
+	List(ctx context.Context) ([]model.Webhook, error)
+	Update(ctx context.Context, webhook *model.Webhook) error
+	// Delete removes a webhook together with its delivery history.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// ListActive returns the webhooks that receive events.
+	ListActive(ctx context.Context) ([]model.Webhook, error)
+
+	// AddDeliveries queues deliveries. A delivery of an event the webhook
+	// already has is skipped, so an event handed over twice is sent once.
+	AddDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error
+	GetDelivery(ctx context.Context, webhookID, id uuid.UUID) (*model.WebhookDelivery, error)
+	// ListDeliveries returns a page of the matching deliveries, newest
+	// first, and the number of matching deliveries in total.
+	ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]model.WebhookDelivery, int64, error)
+	// Redeliver makes a delivery due again right away with a fresh set of
+	// attempts, whatever its status.
+	Redeliver(ctx context.Context, id uuid.UUID) error
+
+	// AddAttempt records an attempt and its outcome on the delivery.
+	AddAttempt(ctx context.Context, attempt *model.WebhookAttempt) error
+	ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]model.WebhookAttempt, error)
+}
+
+func NewWebhookRepository(
+	r *Repository,
+) WebhookRepository {
+	return newWebhookRepository(r)
+}
+
+// NewWebhookDeliveryStore reads the queued deliveries for an
+// events.Dispatcher. The claimed events are deliveries: ID is the delivery
+// and AggregateID the webhook it goes to. Finished deliveries are kept as
+// history instead of being removed.
+func NewWebhookDeliveryStore(r *Repository) events.Store {
+	return newWebhookRepository(r)
+}
+
+func newWebhookRepository(r *Repository) *webhookRepository {
+	return &webhookRepository{
+		Repository: r,
+		now:        time.Now,
+	}
+}
+
+type webhookRepository struct {
+	*Repository
+	now func() time.Time
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *model.Webhook) error {
+	return r.DB(ctx).Create(webhook).Error
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Webhook, error) {
+	var webhook model.Webhook
+	if err := r.DB(ctx).Where("id = ?", id).First(&webhook).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) List(ctx context.Context) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := r.DB(ctx).Order("created_at").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepository) Update(ctx context.Context, webhook *model.Webhook) error {
+	return r.DB(ctx).Save(webhook).Error
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		deliveries := r.DB(ctx).Model(&model.WebhookDelivery{}).Select("id").Where("webhook_id = ?", id)
+		if err := r.DB(ctx).Where("delivery_id IN (?)", deliveries).Delete(&model.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := r.DB(ctx).Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := r.DB(ctx).Where("id = ?", id).Delete(&model.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *webhookRepository) ListActive(ctx context.Context) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := r.DB(ctx).Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepository) AddDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+func (r *webhookRepository) GetDelivery(ctx context.Context, webhookID, id uuid.UUID) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := r.DB(ctx).Where("id = ? AND webhook_id = ?", id, webhookID).First(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]model.WebhookDelivery, int64, error) {
+	query := r.DB(ctx).Model(&model.WebhookDelivery{}).Where("webhook_id = ?", filter.WebhookID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	if err := query.Order("created_at DESC").Order("id").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+func (r *webhookRepository) Redeliver(ctx context.Context, id uuid.UUID) error {
+	now := r.now()
+	return r.DB(ctx).Model(&model.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":       model.WebhookDeliveryPending,
+			"attempts":     0,
+			"available_at": now,
+			"updated_at":   now,
+		}).Error
+}
+
+func (r *webhookRepository) AddAttempt(ctx context.Context, attempt *model.WebhookAttempt) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Create(attempt).Error; err != nil {
+			return err
+		}
+		return r.DB(ctx).Model(&model.WebhookDelivery{}).
+			Where("id = ?", attempt.DeliveryID).
+			Updates(map[string]any{
+				"last_status_code": attempt.StatusCode,
+				"last_error":       attempt.Error,
+				"updated_at":       attempt.CreatedAt,
+			}).Error
+	})
+}
+
+func (r *webhookRepository) ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]model.WebhookAttempt, error) {
+	var attempts []model.WebhookAttempt
+	if err := r.DB(ctx).Where("delivery_id = ?", deliveryID).Order("created_at").Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+func (r *webhookRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]events.Event, error) {
+	now := r.now()
+	var rows []model.WebhookDelivery
+	err := r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND available_at <= ?", model.WebhookDeliveryPending, now).
+			Order("available_at").
+			Limit(limit).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		ids := make([]any, len(rows))
+		for i := range rows {
+			ids[i] = rows[i].Id
+			rows[i].Attempts++
+		}
+		return r.DB(ctx).Model(&model.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Updates(map[string]any{
+				"attempts":     gorm.Expr("attempts + 1"),
+				"available_at": now.Add(lease),
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]events.Event, len(rows))
+	for i, row := range rows {
+		claimed[i] = events.Event{
+			ID:          row.Id.String(),
+			Type:        row.EventType,
+			AggregateID: row.WebhookID.String(),
+			Payload:     []byte(row.Payload),
+			OccurredAt:  row.CreatedAt,
+			Attempts:    row.Attempts,
+		}
+	}
+	return claimed, nil
+}
+
+func (r *webhookRepository) Delivered(ctx context.Context, id string) error {
+	return r.finish(ctx, id, map[string]any{"status": model.WebhookDeliverySucceeded})
+}
+
+func (r *webhookRepository) Retry(ctx context.Context, id string, next time.Time, reason string) error {
+	return r.finish(ctx, id, map[string]any{"available_at": next, "last_error": reason})
+}
+
+func (r *webhookRepository) DeadLetter(ctx context.Context, id string, reason string) error {
+	return r.finish(ctx, id, map[string]any{"status": model.WebhookDeliveryFailed, "last_error": reason})
+}
+
+func (r *webhookRepository) finish(ctx context.Context, id string, updates map[string]any) error {
+	updates["updated_at"] = r.now()
+	return r.DB(ctx).Model(&model.WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error
+}
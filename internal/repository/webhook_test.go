@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/giortzisg/go-boilerplate/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupWebhookRepository(t *testing.T, now time.Time) (*webhookRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+	repo := newWebhookRepository(NewRepository(slog.New(slog.NewJSONHandler(os.Stdout, nil)), db))
+	repo.now = func() time.Time { return now }
+	return repo, mock
+}
+
+func TestWebhookRepository_AddDeliveries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo, mock := setupWebhookRepository(t, now)
+	delivery := model.WebhookDelivery{
+		Id:          uuid.New(),
+		WebhookID:   uuid.New(),
+		EventID:     "evt-1",
+		EventType:   "user.created",
+		Payload:     `{}`,
+		Status:      model.WebhookDeliveryPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "webhook_deliveries" ("id","webhook_id","event_id","event_type","payload","status","attempts","available_at","last_status_code","last_error","created_at","updated_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) ON CONFLICT DO NOTHING`)).
+		WithArgs(delivery.Id, delivery.WebhookID, "evt-1", "user.created", `{}`, model.WebhookDeliveryPending, 0, now, 0, "", now, now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.AddDeliveries(context.Background(), []model.WebhookDelivery{delivery}))
+	assert.NoError(t, repo.AddDeliveries(context.Background(), nil))
+}
+
+func TestWebhookRepository_Redeliver(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo, mock := setupWebhookRepository(t, now)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "attempts"=$1,"available_at"=$2,"status"=$3,"updated_at"=$4 WHERE id = $5`)).
+		WithArgs(0, now, model.WebhookDeliveryPending, now, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Redeliver(context.Background(), id))
+}
+
+func TestWebhookRepository_DeliveredIsKept(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo, mock := setupWebhookRepository(t, now)
+	id := uuid.NewString()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "status"=$1,"updated_at"=$2 WHERE id = $3`)).
+		WithArgs(model.WebhookDeliverySucceeded, now, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Delivered(context.Background(), id))
+}
+
+func TestWebhookRepository_Delete(t *testing.T) {
+	repo, mock := setupWebhookRepository(t, time.Now())
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "webhook_attempts" WHERE delivery_id IN (SELECT "id" FROM "webhook_deliveries" WHERE webhook_id = $1)`)).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "webhook_deliveries" WHERE webhook_id = $1`)).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "webhooks" WHERE id = $1`)).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, repo.Delete(context.Background(), id), gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+)
+
+// Masked replaces the values of sensitive fields in changes.
+const Masked = "[MASKED]"
+
+// Actor is who made a change, identified by the client address. It is
+// empty for changes made by the system itself rather than on behalf of a
+// request.
+type Actor struct {
+	IP string
+}
+
+type ctxKey string
+
+const actorKey ctxKey = "audit_actor"
+
+// WithActor stores the actor of the current request.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns the actor stored by WithActor, or the zero Actor
+// when there is none.
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey).(Actor); ok {
+		return actor
+	}
+	return Actor{}
+}
+
+// Change is the value of a field before and after a change. From is nil
+// for created records and To for deleted ones.
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Diff returns the fields that differ between before and after, either of
+// which may be nil. The values of the sensitive fields are replaced by
+// Masked, so the change shows up without disclosing them.
+func Diff(before, after map[string]any, sensitive ...string) map[string]Change {
+	masked := make(map[string]bool, len(sensitive))
+	for _, field := range sensitive {
+		masked[field] = true
+	}
+	mask := func(field string, value any, ok bool) any {
+		if !ok {
+			return nil
+		}
+		if masked[field] {
+			return Masked
+		}
+		return value
+	}
+
+	changes := map[string]Change{}
+	for field, to := range after {
+		from, ok := before[field]
+		if ok && reflect.DeepEqual(from, to) {
+			continue
+		}
+		changes[field] = Change{From: mask(field, from, ok), To: mask(field, to, true)}
+	}
+	for field, from := range before {
+		if _, ok := after[field]; !ok {
+			changes[field] = Change{From: mask(field, from, true)}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before map[string]any
+		after  map[string]any
+		want   map[string]Change
+	}{
+		{
+			name:  "created",
+			after: map[string]any{"name": "Ann", "password": "hash"},
+			want: map[string]Change{
+				"name":     {To: "Ann"},
+				"password": {To: Masked},
+			},
+		},
+		{
+			name:   "updated",
+			before: map[string]any{"name": "Ann", "email": "ann@example.com", "password": "old"},
+			after:  map[string]any{"name": "Anna", "email": "ann@example.com", "password": "new"},
+			want: map[string]Change{
+				"name":     {From: "Ann", To: "Anna"},
+				"password": {From: Masked, To: Masked},
+			},
+		},
+		{
+			name:   "unchanged secret is left out",
+			before: map[string]any{"name": "Ann", "password": "hash"},
+			after:  map[string]any{"name": "Anna", "password": "hash"},
+			want: map[string]Change{
+				"name": {From: "Ann", To: "Anna"},
+			},
+		},
+		{
+			name:   "deleted",
+			before: map[string]any{"name": "Ann", "password": "hash"},
+			want: map[string]Change{
+				"name":     {From: "Ann"},
+				"password": {From: Masked},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after, "password"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActorFromContext(t *testing.T) {
+	if got := ActorFromContext(context.Background()); got != (Actor{}) {
+		t.Errorf("ActorFromContext() = %v, want the zero Actor", got)
+	}
+
+	ctx := WithActor(context.Background(), Actor{IP: "192.0.2.1"})
+	if got, want := ActorFromContext(ctx), (Actor{IP: "192.0.2.1"}); got != want {
+		t.Errorf("ActorFromContext() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,225 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings as they appear in Accept-Encoding and Content-Encoding.
+const (
+	Gzip     = "gzip"
+	Deflate  = "deflate"
+	Zstd     = "zstd"
+	Brotli   = "br"
+	Identity = "identity"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// DefaultPreference lists the encodings in the order the server prefers
+// them when a client accepts several equally.
+var DefaultPreference = []string{Zstd, Brotli, Gzip, Deflate}
+
+// Writer compresses into the writer it was created for. Close writes the
+// trailer and releases the Writer, which must not be used afterwards.
+type Writer interface {
+	io.WriteCloser
+	Flush() error
+}
+
+type Encoding interface {
+	Name() string
+	NewWriter(w io.Writer) Writer
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+type resetWriter interface {
+	Writer
+	Reset(w io.Writer)
+}
+
+// encoding reuses its writers, since setting one up allocates far more
+// than a typical response body.
+type encoding struct {
+	name      string
+	pool      sync.Pool
+	newReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+func newEncoding(name string, newWriter func() resetWriter, newReader func(r io.Reader) (io.ReadCloser, error)) *encoding {
+	return &encoding{
+		name:      name,
+		pool:      sync.Pool{New: func() any { return newWriter() }},
+		newReader: newReader,
+	}
+}
+
+func (e *encoding) Name() string { return e.name }
+
+func (e *encoding) NewWriter(w io.Writer) Writer {
+	zw := e.pool.Get().(resetWriter)
+	zw.Reset(w)
+	return &pooledWriter{resetWriter: zw, encoding: e}
+}
+
+func (e *encoding) NewReader(r io.Reader) (io.ReadCloser, error) { return e.newReader(r) }
+
+type pooledWriter struct {
+	resetWriter
+	encoding *encoding
+	closed   bool
+}
+
+func (w *pooledWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.resetWriter.Close()
+	// drop the reference to the destination before pooling
+	w.resetWriter.Reset(io.Discard)
+	w.encoding.pool.Put(w.resetWriter)
+	return err
+}
+
+var encodings = map[string]Encoding{
+	Gzip: newEncoding(Gzip,
+		func() resetWriter { return gzip.NewWriter(io.Discard) },
+		func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	),
+	Deflate: newEncoding(Deflate,
+		func() resetWriter { return zlib.NewWriter(io.Discard) },
+		zlib.NewReader,
+	),
+	Zstd: newEncoding(Zstd,
+		func() resetWriter {
+			zw, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+			return zw
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(64<<20))
+			if err != nil {
+				return nil, err
+			}
+			return zr.IOReadCloser(), nil
+		},
+	),
+	Brotli: newEncoding(Brotli,
+		// the default level is tuned for static assets and too slow per request
+		func() resetWriter { return brotli.NewWriterLevel(io.Discard, 4) },
+		func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(brotli.NewReader(r)), nil },
+	),
+}
+
+func normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "x-gzip" {
+		return Gzip
+	}
+	return name
+}
+
+// Lookup returns the encoding for a content coding name.
+func Lookup(name string) (Encoding, bool) {
+	e, ok := encodings[normalize(name)]
+	return e, ok
+}
+
+// Negotiate picks the encoding from preference the client rates highest in
+// an Accept-Encoding value, falling back to the order of preference on
+// ties. It returns "" when the response should not be encoded.
+func Negotiate(acceptEncoding string, preference []string) string {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return ""
+	}
+
+	accepted := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err != nil || parsed < 0 || parsed > 1 {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+
+		if name = normalize(name); name == "*" {
+			wildcard = q
+		} else if name != "" {
+			accepted[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range preference {
+		q, ok := accepted[name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// NewReader undoes the codings listed in a Content-Encoding value, which
+// were applied in the order given. Closing the result releases every
+// decoder but not r.
+func NewReader(contentEncoding string, r io.Reader) (io.ReadCloser, error) {
+	var names []string
+	for _, name := range strings.Split(contentEncoding, ",") {
+		if name = normalize(name); name != "" && name != Identity {
+			names = append(names, name)
+		}
+	}
+
+	readers := make(multiCloser, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		e, ok := encodings[names[i]]
+		if !ok {
+			_ = readers.Close()
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, names[i])
+		}
+		zr, err := e.NewReader(r)
+		if err != nil {
+			_ = readers.Close()
+			return nil, err
+		}
+		readers = append(readers, zr)
+		r = zr
+	}
+	return &decodedReader{Reader: r, closers: readers}, nil
+}
+
+type multiCloser []io.ReadCloser
+
+func (c multiCloser) Close() error {
+	var errs []error
+	for i := len(c) - 1; i >= 0; i-- {
+		errs = append(errs, c[i].Close())
+	}
+	return errors.Join(errs...)
+}
+
+type decodedReader struct {
+	io.Reader
+	closers multiCloser
+}
+
+func (r *decodedReader) Close() error { return r.closers.Close() }
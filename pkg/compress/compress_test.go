@@ -0,0 +1,80 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{name: "no header", acceptEncoding: "", want: ""},
+		{name: "server preference breaks ties", acceptEncoding: "gzip, br, zstd", want: Zstd},
+		{name: "client quality wins", acceptEncoding: "gzip;q=1, zstd;q=0.5", want: Gzip},
+		{name: "x-gzip alias", acceptEncoding: "x-gzip", want: Gzip},
+		{name: "wildcard", acceptEncoding: "*", want: Zstd},
+		{name: "wildcard with exclusion", acceptEncoding: "*, zstd;q=0, br;q=0", want: Gzip},
+		{name: "identity only", acceptEncoding: "identity", want: ""},
+		{name: "unknown coding", acceptEncoding: "compress", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.acceptEncoding, DefaultPreference); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat(`{"name":"Test User","email":"test@example.com"}`, 100))
+
+	for _, name := range DefaultPreference {
+		t.Run(name, func(t *testing.T) {
+			encoding, ok := Lookup(name)
+			if !ok {
+				t.Fatalf("Lookup(%q) not found", name)
+			}
+
+			// twice, so the second pass runs on a pooled writer
+			for i := 0; i < 2; i++ {
+				var buf bytes.Buffer
+				w := encoding.NewWriter(&buf)
+				if _, err := w.Write(data); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatalf("Close() error = %v", err)
+				}
+				if buf.Len() >= len(data) {
+					t.Errorf("compressed %d bytes into %d", len(data), buf.Len())
+				}
+
+				r, err := NewReader(name, &buf)
+				if err != nil {
+					t.Fatalf("NewReader() error = %v", err)
+				}
+				got, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("ReadAll() error = %v", err)
+				}
+				_ = r.Close()
+				if !bytes.Equal(got, data) {
+					t.Errorf("round trip mismatch")
+				}
+			}
+		})
+	}
+}
+
+func TestNewReader_Unsupported(t *testing.T) {
+	if _, err := NewReader("compress", strings.NewReader("")); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("NewReader() error = %v, want %v", err, ErrUnsupportedEncoding)
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"github.com/giortzisg/go-boilerplate/pkg/logging"
+	"github.com/spf13/viper"
+)
+
+// Logging reads the log settings.
+func Logging(conf *viper.Viper) logging.Config {
+	return logging.Config{
+		Level:     conf.GetString("log.level"),
+		Format:    conf.GetString("log.format"),
+		Output:    conf.GetString("log.output"),
+		AddSource: conf.GetBool("log.add_source"),
+		Packages:  conf.GetStringMapString("log.packages"),
+	}
+}
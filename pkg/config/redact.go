@@ -0,0 +1,52 @@
+package config
+
+import "strings"
+
+// Redacted replaces the values of sensitive settings.
+const Redacted = "[REDACTED]"
+
+// sensitiveNames are parts of setting names whose values must not be shown.
+var sensitiveNames = []string{"password", "secret", "token", "dsn", "credential", "private"}
+
+// Redact returns a copy of settings, as returned by viper's AllSettings,
+// with the values of sensitive settings replaced by Redacted. A setting is
+// sensitive when its name contains one of a few well known words, such as
+// "password" or "dsn", or ends in "_key".
+func Redact(settings map[string]any) map[string]any {
+	redacted := make(map[string]any, len(settings))
+	for name, value := range settings {
+		if sensitive(name) {
+			redacted[name] = Redacted
+			continue
+		}
+		redacted[name] = redactValue(value)
+	}
+	return redacted
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return Redact(v)
+	case []any:
+		values := make([]any, len(v))
+		for i := range v {
+			values[i] = redactValue(v[i])
+		}
+		return values
+	}
+	return value
+}
+
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, "_key") {
+		return true
+	}
+	for _, word := range sensitiveNames {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
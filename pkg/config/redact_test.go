@@ -0,0 +1,49 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	settings := map[string]any{
+		"env": "local",
+		"data": map[string]any{
+			"db": map[string]any{
+				"user": map[string]any{"driver": "postgres", "dsn": "postgres://app:hunter2@db/app"},
+			},
+		},
+		"http": map[string]any{
+			"rate_limit": map[string]any{"key": "ip", "api_key_header": "X-API-Key"},
+			"tls":        map[string]any{"key_file": "certs/server.key"},
+		},
+		"webhooks": []any{
+			map[string]any{"url": "https://example.com", "signing_key": "abc", "Client_Secret": "def"},
+		},
+		"auth": map[string]any{"password_pepper": "xyz", "token": map[string]any{"ttl": "1h"}},
+	}
+
+	want := map[string]any{
+		"env": "local",
+		"data": map[string]any{
+			"db": map[string]any{
+				"user": map[string]any{"driver": "postgres", "dsn": Redacted},
+			},
+		},
+		"http": map[string]any{
+			"rate_limit": map[string]any{"key": "ip", "api_key_header": "X-API-Key"},
+			"tls":        map[string]any{"key_file": "certs/server.key"},
+		},
+		"webhooks": []any{
+			map[string]any{"url": "https://example.com", "signing_key": Redacted, "Client_Secret": Redacted},
+		},
+		"auth": map[string]any{"password_pepper": Redacted, "token": Redacted},
+	}
+
+	if got := Redact(settings); !reflect.DeepEqual(got, want) {
+		t.Errorf("Redact() = %v, want %v", got, want)
+	}
+	if settings["data"].(map[string]any)["db"].(map[string]any)["user"].(map[string]any)["dsn"] == Redacted {
+		t.Error("Redact() modified its input")
+	}
+}
@@ -0,0 +1,131 @@
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader   = "X-Request-ID"
+	TraceParentHeader = "traceparent"
+
+	// maxRequestIDLength keeps client supplied ids from bloating every log line
+	maxRequestIDLength = 128
+)
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	traceParentKey
+)
+
+// NewRequestID generates a request id for requests that did not bring one.
+func NewRequestID() string {
+	return uuid.NewString()
+}
+
+// ValidRequestID reports whether a client supplied id is safe to propagate:
+// non-empty, bounded in length and made of printable ASCII only.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request id stored in ctx, or "" when there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// TraceParent is a W3C Trace Context traceparent header, version 00.
+type TraceParent struct {
+	TraceID  string
+	ParentID string
+	Flags    byte
+}
+
+// NewTraceParent starts a new, sampled trace.
+func NewTraceParent() TraceParent {
+	return TraceParent{TraceID: randomHex(16), ParentID: randomHex(8), Flags: 0x01}
+}
+
+// ParseTraceParent parses a traceparent header, rejecting the all-zero ids
+// and malformed values as the specification requires.
+func ParseTraceParent(header string) (TraceParent, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return TraceParent{}, false
+	}
+	// version 00 has exactly four fields, future versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return TraceParent{}, false
+	}
+
+	traceID, parentID, flags := parts[1], parts[2], parts[3]
+	if !isLowerHex(parts[0]) || !isLowerHex(traceID) || len(traceID) != 32 || isZero(traceID) ||
+		!isLowerHex(parentID) || len(parentID) != 16 || isZero(parentID) ||
+		!isLowerHex(flags) || len(flags) != 2 {
+		return TraceParent{}, false
+	}
+
+	var f byte
+	if _, err := fmt.Sscanf(flags, "%02x", &f); err != nil {
+		return TraceParent{}, false
+	}
+	return TraceParent{TraceID: traceID, ParentID: parentID, Flags: f}, true
+}
+
+// Child returns the traceparent for a new span within the same trace.
+func (t TraceParent) Child() TraceParent {
+	return TraceParent{TraceID: t.TraceID, ParentID: randomHex(8), Flags: t.Flags}
+}
+
+func (t TraceParent) String() string {
+	return fmt.Sprintf("00-%s-%s-%02x", t.TraceID, t.ParentID, t.Flags)
+}
+
+func WithTraceParent(ctx context.Context, t TraceParent) context.Context {
+	return context.WithValue(ctx, traceParentKey, t)
+}
+
+// TraceParentFromContext returns the traceparent of the current request.
+func TraceParentFromContext(ctx context.Context) (TraceParent, bool) {
+	t, ok := ctx.Value(traceParentKey).(TraceParent)
+	return t, ok
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
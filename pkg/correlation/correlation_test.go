@@ -0,0 +1,98 @@
+package correlation
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "4bf92f35-77b3-4da6-a3ce-929d0e0e4736", want: true},
+		{id: "", want: false},
+		{id: "with space", want: false},
+		{id: "line\nbreak", want: false},
+		{id: strings.Repeat("a", maxRequestIDLength+1), want: false},
+	}
+	for _, tt := range tests {
+		if got := ValidRequestID(tt.id); got != tt.want {
+			t.Errorf("ValidRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   TraceParent
+		wantOk bool
+	}{
+		{
+			name:   "valid sampled",
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			want:   TraceParent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7", Flags: 0x01},
+			wantOk: true,
+		},
+		{name: "empty", header: ""},
+		{name: "invalid version", header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "zero trace id", header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero parent id", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "uppercase hex", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "extra field on version 00", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-xx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTraceParent(tt.header)
+			if ok != tt.wantOk {
+				t.Fatalf("ParseTraceParent(%q) ok = %v, want %v", tt.header, ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("ParseTraceParent(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+			if ok && got.String() != tt.header {
+				t.Errorf("String() = %q, want %q", got.String(), tt.header)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(
+		WithRequestID(context.Background(), "req-1"),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}),
+	)
+	logger.InfoContext(ctx, "with ids")
+	logger.Info("without ids")
+	// without a span, the traceparent stored by the middleware is used
+	parent := TraceParent{TraceID: "0af7651916cd43dd8448eb211c80319c", ParentID: "b7ad6b7169203331", Flags: 0x01}
+	logger.InfoContext(WithTraceParent(context.Background(), parent), "with traceparent")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 log lines, got %d", len(lines))
+	}
+	for _, want := range []string{`"request_id":"req-1"`, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`, `"span_id":"00f067aa0ba902b7"`} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("expected %s in %s", want, lines[0])
+		}
+	}
+	if strings.Contains(lines[1], "request_id") || strings.Contains(lines[1], "trace_id") {
+		t.Errorf("expected no correlation ids in %s", lines[1])
+	}
+	if !strings.Contains(lines[2], `"trace_id":"`+parent.TraceID+`"`) || !strings.Contains(lines[2], `"span_id":"`+parent.ParentID+`"`) {
+		t.Errorf("expected traceparent ids in %s", lines[2])
+	}
+}
@@ -1,5 +1,7 @@
 package error
 
+import "time"
+
 type StatusError struct {
 	error
 	code int
@@ -19,3 +21,21 @@ func (e *StatusError) Unwrap() error {
 func (e *StatusError) HTTPStatus() int {
 	return e.code
 }
+
+// RetryError is a StatusError that also tells the client how long to wait
+// before trying again.
+type RetryError struct {
+	*StatusError
+	retryAfter time.Duration
+}
+
+func NewRetryError(err error, code int, retryAfter time.Duration) *RetryError {
+	return &RetryError{
+		StatusError: NewStatusError(err, code),
+		retryAfter:  retryAfter,
+	}
+}
+
+func (e *RetryError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
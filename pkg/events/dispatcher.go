@@ -0,0 +1,276 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Defaults of a Dispatcher.
+const (
+	DefaultPollInterval    = time.Second
+	DefaultBatchSize       = 100
+	DefaultMaxAttempts     = 10
+	DefaultRetryBackoff    = time.Second
+	DefaultMaxRetryBackoff = 5 * time.Minute
+	DefaultLease           = time.Minute
+)
+
+type subscription struct {
+	name    string
+	handler Handler
+}
+
+type Option func(d *Dispatcher)
+
+// WithPollInterval sets how often the outbox is checked for due events.
+func WithPollInterval(d time.Duration) Option {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.pollInterval = d
+	}
+}
+
+// WithBatchSize sets how many events are claimed at once.
+func WithBatchSize(n int) Option {
+	return func(d *Dispatcher) {
+		d.batchSize = n
+	}
+}
+
+// WithMaxAttempts sets how many times an event is tried before it is
+// dead-lettered.
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = n
+	}
+}
+
+// WithRetryBackoff sets the delay before the first retry, which doubles
+// with every further attempt up to max.
+func WithRetryBackoff(base, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.retryBackoff = base
+		d.maxRetryBackoff = max
+	}
+}
+
+// WithLease sets how long claimed events stay hidden from other
+// dispatchers. It must cover delivering a whole batch; events of a
+// dispatcher that dies are delivered again once it runs out.
+func WithLease(d time.Duration) Option {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.lease = d
+	}
+}
+
+// Dispatcher delivers the events of an outbox to the subscribers of their
+// type. It runs as a server.Server next to the HTTP servers. An event is
+// done once every subscriber handled it; when any of them fails, all of
+// them get it again after a backoff, so delivery is at least once.
+type Dispatcher struct {
+	logger *slog.Logger
+	store  Store
+	now    func() time.Time
+
+	mu          sync.RWMutex
+	subscribers map[string][]subscription
+
+	pollInterval    time.Duration
+	batchSize       int
+	maxAttempts     int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+	lease           time.Duration
+
+	// runMu guards started and stopped, so that Stop either waits for a
+	// running Start or keeps a later one from delivering at all
+	runMu   sync.Mutex
+	started bool
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewDispatcher(logger *slog.Logger, store Store, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		logger:          logger,
+		store:           store,
+		now:             time.Now,
+		subscribers:     map[string][]subscription{},
+		pollInterval:    DefaultPollInterval,
+		batchSize:       DefaultBatchSize,
+		maxAttempts:     DefaultMaxAttempts,
+		retryBackoff:    DefaultRetryBackoff,
+		maxRetryBackoff: DefaultMaxRetryBackoff,
+		lease:           DefaultLease,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Subscribe registers handler for events of eventType, or for every event
+// with AllEvents. name identifies the subscriber in logs.
+func (d *Dispatcher) Subscribe(eventType, name string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscribers[eventType] = append(d.subscribers[eventType], subscription{name: name, handler: handler})
+}
+
+// SubscribeSink publishes every event to sink.
+func (d *Dispatcher) SubscribeSink(name string, sink Sink) {
+	d.Subscribe(AllEvents, name, sink.Publish)
+}
+
+// Start delivers due events until ctx is done or Stop is called. A
+// Dispatcher that was stopped before it started delivers nothing.
+func (d *Dispatcher) Start(ctx context.Context) error {
+	if !d.begin() {
+		return nil
+	}
+	defer close(d.done)
+	d.logger.Info("Starting event dispatcher", "poll_interval", d.pollInterval.String())
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		// keep going while there is a backlog
+		for {
+			n, err := d.Dispatch(ctx)
+			if err != nil {
+				d.logger.ErrorContext(ctx, "Error dispatching events", "error", err)
+			}
+			if err != nil || n < d.batchSize {
+				break
+			}
+			if ctx.Err() != nil || d.stopping() {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop stops the dispatcher after the batch being delivered and waits
+// until it has, or until ctx is done.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.runMu.Lock()
+	if !d.stopped {
+		d.stopped = true
+		close(d.stop)
+	}
+	started := d.started
+	d.runMu.Unlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// begin marks the dispatcher as started unless it was already stopped.
+func (d *Dispatcher) begin() bool {
+	d.runMu.Lock()
+	defer d.runMu.Unlock()
+	if d.stopped {
+		return false
+	}
+	d.started = true
+	return true
+}
+
+func (d *Dispatcher) stopping() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Dispatch delivers one batch of due events and returns how many it
+// claimed.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	batch, err := d.store.Claim(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	// a claimed batch is finished even when stopping, so its events are
+	// not left waiting for the lease to run out
+	ctx = context.WithoutCancel(ctx)
+	var errs []error
+	for _, event := range batch {
+		if err := d.finish(ctx, event, d.deliver(ctx, event)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(batch), errors.Join(errs...)
+}
+
+// deliver hands event to all its subscribers, returning their errors.
+func (d *Dispatcher) deliver(ctx context.Context, event Event) error {
+	d.mu.RLock()
+	subscribers := append(append([]subscription(nil), d.subscribers[event.Type]...), d.subscribers[AllEvents]...)
+	d.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := d.handle(ctx, s, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Dispatcher) handle(ctx context.Context, s subscription, event Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return s.handler(ctx, event)
+}
+
+// finish records the outcome of delivering event.
+func (d *Dispatcher) finish(ctx context.Context, event Event, err error) error {
+	if err == nil {
+		return d.store.Delivered(ctx, event.ID)
+	}
+
+	if event.Attempts >= d.maxAttempts {
+		d.logger.ErrorContext(ctx, "Event dead-lettered", "event_id", event.ID, "event_type", event.Type, "attempts", event.Attempts, "error", err)
+		return d.store.DeadLetter(ctx, event.ID, err.Error())
+	}
+	next := d.now().Add(d.backoff(event.Attempts))
+	d.logger.WarnContext(ctx, "Event delivery failed, retrying", "event_id", event.ID, "event_type", event.Type, "attempts", event.Attempts, "retry_at", next, "error", err)
+	return d.store.Retry(ctx, event.ID, next, err.Error())
+}
+
+// backoff returns the delay before the retry following attempt.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.retryBackoff
+	for i := 1; i < attempt && delay < d.maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.maxRetryBackoff)
+}
@@ -0,0 +1,210 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is an outbox kept in memory.
+type memoryStore struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	pending   map[string]*Event
+	due       map[string]time.Time
+	reasons   map[string]string
+	delivered []string
+	dead      []string
+}
+
+func newMemoryStore(now func() time.Time, events ...Event) *memoryStore {
+	s := &memoryStore{now: now, pending: map[string]*Event{}, due: map[string]time.Time{}, reasons: map[string]string{}}
+	for i := range events {
+		s.pending[events[i].ID] = &events[i]
+		s.due[events[i].ID] = time.Time{}
+	}
+	return s
+}
+
+func (s *memoryStore) Claim(_ context.Context, limit int, lease time.Duration) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []Event
+	for id, event := range s.pending {
+		if len(claimed) == limit || s.due[id].After(s.now()) {
+			continue
+		}
+		event.Attempts++
+		s.due[id] = s.now().Add(lease)
+		claimed = append(claimed, *event)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) Delivered(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+	s.delivered = append(s.delivered, id)
+	return nil
+}
+
+func (s *memoryStore) Retry(_ context.Context, id string, next time.Time, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.due[id] = next
+	s.reasons[id] = reason
+	return nil
+}
+
+func (s *memoryStore) DeadLetter(_ context.Context, id string, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+	s.reasons[id] = reason
+	s.dead = append(s.dead, id)
+	return nil
+}
+
+type sinkFunc func(ctx context.Context, event Event) error
+
+func (f sinkFunc) Publish(ctx context.Context, event Event) error { return f(ctx, event) }
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := newMemoryStore(clock,
+		Event{ID: "1", Type: "user.created"},
+		Event{ID: "2", Type: "user.updated"},
+	)
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), store,
+		WithMaxAttempts(3),
+		WithRetryBackoff(time.Second, 3*time.Second),
+	)
+	d.now = clock
+
+	var created, all []string
+	failUpdates := 2
+	d.Subscribe("user.created", "welcome", func(_ context.Context, event Event) error {
+		created = append(created, event.ID)
+		return nil
+	})
+	d.SubscribeSink("sink", sinkFunc(func(_ context.Context, event Event) error {
+		all = append(all, event.ID)
+		if event.Type == "user.updated" && failUpdates > 0 {
+			failUpdates--
+			return errors.New("sink unavailable")
+		}
+		return nil
+	}))
+
+	if n, err := d.Dispatch(context.Background()); err != nil || n != 2 {
+		t.Fatalf("Dispatch() = %d, %v, want 2 events", n, err)
+	}
+	if len(store.delivered) != 1 || store.delivered[0] != "1" {
+		t.Errorf("delivered = %v, want [1]", store.delivered)
+	}
+	if got := store.due["2"]; !got.Equal(now.Add(time.Second)) {
+		t.Errorf("retry at %v, want after 1s", got)
+	}
+	if store.reasons["2"] != "sink: sink unavailable" {
+		t.Errorf("reason = %q", store.reasons["2"])
+	}
+
+	// not due yet
+	if n, _ := d.Dispatch(context.Background()); n != 0 {
+		t.Errorf("Dispatch() claimed %d events before the retry was due", n)
+	}
+
+	now = now.Add(time.Second)
+	_, _ = d.Dispatch(context.Background())
+	if got := store.due["2"]; !got.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("second retry at %v, want after 2s", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	_, _ = d.Dispatch(context.Background())
+	if len(store.delivered) != 2 {
+		t.Errorf("delivered = %v, want both events", store.delivered)
+	}
+	if len(created) != 1 {
+		t.Errorf("user.created subscriber got %v, want only the created event once", created)
+	}
+	if len(all) != 4 {
+		t.Errorf("sink got %v, want 1 created and 3 update deliveries", all)
+	}
+}
+
+func TestDispatcher_DeadLetter(t *testing.T) {
+	now := time.Now()
+	store := newMemoryStore(func() time.Time { return now }, Event{ID: "1", Type: "user.deleted"})
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), store, WithMaxAttempts(2))
+	d.Subscribe(AllEvents, "broken", func(context.Context, Event) error {
+		panic("boom")
+	})
+
+	_, _ = d.Dispatch(context.Background())
+	now = now.Add(time.Hour)
+	_, _ = d.Dispatch(context.Background())
+
+	if len(store.dead) != 1 || store.reasons["1"] != "broken: panic: boom" {
+		t.Errorf("dead = %v, reason = %q, want the event dead-lettered", store.dead, store.reasons["1"])
+	}
+	now = now.Add(time.Hour)
+	if n, _ := d.Dispatch(context.Background()); n != 0 {
+		t.Errorf("dead-lettered event was claimed again")
+	}
+}
+
+func TestDispatcher_StartStop(t *testing.T) {
+	store := newMemoryStore(time.Now, Event{ID: "1", Type: "user.created"})
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), store, WithPollInterval(10*time.Millisecond))
+	delivered := make(chan string, 1)
+	d.Subscribe("user.created", "test", func(_ context.Context, event Event) error {
+		delivered <- event.ID
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- d.Start(context.Background()) }()
+
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+	if err := d.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+}
+
+func TestDispatcher_StopBeforeStart(t *testing.T) {
+	store := newMemoryStore(time.Now, Event{ID: "1", Type: "user.created"})
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), store, WithPollInterval(10*time.Millisecond))
+	d.Subscribe("user.created", "test", func(context.Context, Event) error { return nil })
+
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- d.Start(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start() kept running after Stop")
+	}
+	if len(store.delivered) != 0 {
+		t.Errorf("delivered %v after Stop, want none", store.delivered)
+	}
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// AllEvents subscribes to every event type.
+const AllEvents = "*"
+
+// Event is a domain event read from the outbox. Payload is the JSON
+// encoded event body and Attempts counts deliveries, including the current
+// one.
+type Event struct {
+	ID          string
+	Type        string
+	AggregateID string
+	Payload     []byte
+	OccurredAt  time.Time
+	Attempts    int
+}
+
+// Handler reacts to an event. Events are delivered at least once, so
+// handlers must tolerate seeing the same event again, and a handler that
+// returns an error gets the event again later.
+type Handler func(ctx context.Context, event Event) error
+
+// Sink publishes events outside of the process, such as to a message
+// broker or to webhooks.
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Store is the outbox events are written to, in the same transaction as
+// the change they describe, and read back from for delivery.
+type Store interface {
+	// Claim returns up to limit events that are due for delivery and
+	// hides them from other claims for lease, so that several dispatchers
+	// can share a store. Attempts is counted up by the claim.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error)
+	// Delivered removes an event that every subscriber has handled.
+	Delivered(ctx context.Context, id string) error
+	// Retry makes an event due again at next, recording why it failed.
+	Retry(ctx context.Context, id string, next time.Time, reason string) error
+	// DeadLetter sets aside an event that ran out of attempts, so that it
+	// is kept for inspection but never delivered again.
+	DeadLetter(ctx context.Context, id string, reason string) error
+}
@@ -0,0 +1,218 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/giortzisg/go-boilerplate/pkg/json"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc reports whether a dependency is usable. It should honor ctx,
+// which carries the per-check timeout.
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status    string    `json:"status"`
+	Latency   string    `json:"latency"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+
+	mu     sync.Mutex
+	result CheckResult
+	expiry time.Time
+}
+
+// Registry runs liveness and readiness checks on behalf of the /healthz and
+// /readyz probes. Results are cached for a while so that frequent probes
+// from several load balancers do not turn into a stream of database pings.
+type Registry struct {
+	mu        sync.RWMutex
+	liveness  []*check
+	readiness []*check
+
+	cacheTTL time.Duration
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+type Option func(r *Registry)
+
+// WithCacheTTL sets how long a check result is reused. Zero disables caching.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(r *Registry) {
+		r.cacheTTL = ttl
+	}
+}
+
+// WithTimeout bounds how long a single check may run.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = timeout
+	}
+}
+
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		cacheTTL: 5 * time.Second,
+		timeout:  2 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// AddLivenessCheck registers a check whose failure means the process should
+// be restarted. Keep these free of external dependencies.
+func (r *Registry) AddLivenessCheck(name string, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness = append(r.liveness, &check{name: name, fn: fn})
+}
+
+// AddReadinessCheck registers a check whose failure means the process
+// should temporarily receive no traffic.
+func (r *Registry) AddReadinessCheck(name string, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness = append(r.readiness, &check{name: name, fn: fn})
+}
+
+// Drain makes readiness fail from now on, so load balancers stop routing
+// new requests while in-flight ones complete.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Liveness runs the liveness checks.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := r.liveness
+	r.mu.RUnlock()
+	return r.run(ctx, checks)
+}
+
+// Readiness runs the readiness checks and fails while draining.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := r.readiness
+	r.mu.RUnlock()
+
+	report := r.run(ctx, checks)
+	if r.Draining() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = CheckResult{
+			Status:    StatusFail,
+			Latency:   time.Duration(0).String(),
+			Error:     "server is shutting down",
+			CheckedAt: time.Now(),
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, checks []*check) Report {
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c *check) {
+			defer wg.Done()
+			result := r.result(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// result returns the cached result of c or runs it. Holding the lock while
+// running makes concurrent probes share a single execution.
+func (r *Registry) result(ctx context.Context, c *check) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(c.expiry) {
+		return c.result
+	}
+
+	// the result is shared with other probes, so a caller going away must
+	// not turn into a cached failure
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+	defer cancel()
+
+	err := c.fn(ctx)
+	result := CheckResult{
+		Status:    StatusOK,
+		Latency:   time.Since(now).String(),
+		CheckedAt: now,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	c.result = result
+	c.expiry = now.Add(r.cacheTTL)
+	return result
+}
+
+// LivenessHandler serves the liveness report, answering 503 on failure.
+func (r *Registry) LivenessHandler() http.Handler {
+	return reportHandler(r.Liveness)
+}
+
+// ReadinessHandler serves the readiness report, answering 503 on failure.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return reportHandler(r.Readiness)
+}
+
+func reportHandler(run func(ctx context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := run(req.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.Encoder(w, req, &report, status); err != nil {
+			http.Error(w, report.Status, status)
+		}
+	})
+}
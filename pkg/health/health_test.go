@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, h http.Handler) (int, Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	return w.Code, report
+}
+
+func TestRegistry_Readiness(t *testing.T) {
+	registry := NewRegistry()
+	registry.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+	registry.AddReadinessCheck("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	status, report := serve(t, registry.ReadinessHandler())
+	if status != http.StatusServiceUnavailable || report.Status != StatusFail {
+		t.Errorf("expected failing readiness, got %d %s", status, report.Status)
+	}
+	if report.Checks["database"].Status != StatusOK {
+		t.Errorf("expected database check to pass, got %+v", report.Checks["database"])
+	}
+	if got := report.Checks["cache"]; got.Status != StatusFail || got.Error != "connection refused" {
+		t.Errorf("expected cache check to fail, got %+v", got)
+	}
+}
+
+func TestRegistry_Caching(t *testing.T) {
+	calls := 0
+	registry := NewRegistry(WithCacheTTL(time.Minute))
+	registry.AddReadinessCheck("database", func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if status, _ := serve(t, registry.ReadinessHandler()); status != http.StatusOK {
+			t.Fatalf("expected readiness to pass, got %d", status)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the check to run once, ran %d times", calls)
+	}
+}
+
+func TestRegistry_Drain(t *testing.T) {
+	registry := NewRegistry()
+	registry.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+
+	if status, _ := serve(t, registry.ReadinessHandler()); status != http.StatusOK {
+		t.Fatalf("expected readiness to pass before draining, got %d", status)
+	}
+
+	registry.Drain()
+
+	if status, report := serve(t, registry.ReadinessHandler()); status != http.StatusServiceUnavailable || report.Checks["shutdown"].Status != StatusFail {
+		t.Errorf("expected readiness to fail while draining, got %d %+v", status, report)
+	}
+	if status, _ := serve(t, registry.LivenessHandler()); status != http.StatusOK {
+		t.Errorf("expected liveness to pass while draining, got %d", status)
+	}
+}
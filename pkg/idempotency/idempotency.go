@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Header is the request header carrying the client chosen key.
+const Header = "Idempotency-Key"
+
+// DefaultTTL is how long a stored response is replayed for.
+const DefaultTTL = 24 * time.Hour
+
+// DefaultLease is how long a key stays claimed by a request that has not
+// finished. Once it runs out the key can be claimed again, so a request
+// that died without releasing its key does not block retries.
+const DefaultLease = time.Minute
+
+// Response is what a request produced, replayed verbatim on retries.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is the state of a key: the fingerprint of the request that first
+// used it and, once that request finished, its Response.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+}
+
+// InFlight reports whether the request that claimed the key has not
+// finished yet.
+func (r *Record) InFlight() bool {
+	return r.Response == nil
+}
+
+// Store keeps idempotency records. Records whose lease or TTL has run out
+// are treated as if they did not exist.
+type Store interface {
+	// Begin claims key for a request with fingerprint for the length of
+	// lease. When the key is already held, the existing record is returned
+	// and claimed is false.
+	Begin(ctx context.Context, key, fingerprint string, lease time.Duration) (record *Record, claimed bool, err error)
+	// Complete stores the response of the request holding key and keeps it
+	// for ttl.
+	Complete(ctx context.Context, key string, response *Response, ttl time.Duration) error
+	// Release drops key so that the request can be retried from scratch.
+	Release(ctx context.Context, key string) error
+}
+
+// Fingerprint identifies a request by method, target, body and the headers
+// that say how to read the body, so a key reused for a different request
+// can be told apart from a retry. The same bytes sent as another content
+// type or encoding are a different request.
+func Fingerprint(method, target string, header http.Header, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + target + "\n"))
+	h.Write([]byte(header.Get("Content-Type") + "\n"))
+	h.Write([]byte(header.Get("Content-Encoding") + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,82 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type memoryRecord struct {
+	Record
+	expiresAt time.Time
+}
+
+// MemoryStore keeps records in process memory. Retries are only recognized
+// when they reach the same replica, so use a shared store when running
+// more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]*memoryRecord
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: map[string]*memoryRecord{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, lease time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if r, ok := s.records[key]; ok && now.Before(r.expiresAt) {
+		record := r.Record
+		return &record, false, nil
+	}
+
+	s.records[key] = &memoryRecord{
+		Record:    Record{Fingerprint: fingerprint},
+		expiresAt: now.Add(lease),
+	}
+	return &Record{Fingerprint: fingerprint}, true, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, response *Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok {
+		r.Response = response
+		r.expiresAt = s.now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, r := range s.records {
+		if !now.Before(r.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Lease(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, claimed, _ := store.Begin(ctx, "key", "fingerprint", time.Minute); !claimed {
+		t.Fatal("Begin() did not claim a fresh key")
+	}
+	record, claimed, _ := store.Begin(ctx, "key", "fingerprint", time.Minute)
+	if claimed || !record.InFlight() {
+		t.Fatalf("Begin() during the lease: claimed %v, in flight %v", claimed, record.InFlight())
+	}
+
+	// the request holding the key never finished
+	now = now.Add(time.Minute)
+	if _, claimed, _ := store.Begin(ctx, "key", "fingerprint", time.Minute); !claimed {
+		t.Fatal("Begin() did not reclaim a key past its lease")
+	}
+
+	// a stored response outlives the lease
+	if err := store.Complete(ctx, "key", &Response{Status: http.StatusCreated}, time.Hour); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	now = now.Add(30 * time.Minute)
+	record, claimed, _ = store.Begin(ctx, "key", "fingerprint", time.Minute)
+	if claimed || record.InFlight() || record.Response.Status != http.StatusCreated {
+		t.Fatalf("Begin() within the TTL: claimed %v, record %+v", claimed, record)
+	}
+
+	now = now.Add(30 * time.Minute)
+	if _, claimed, _ := store.Begin(ctx, "key", "fingerprint", time.Minute); !claimed {
+		t.Fatal("Begin() did not reclaim a key past its TTL")
+	}
+}
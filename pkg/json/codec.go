@@ -0,0 +1,175 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	JSONContentType    = "application/json"
+	MsgPackContentType = "application/msgpack"
+	CBORContentType    = "application/cbor"
+)
+
+// Codec reads and writes request and response bodies for one media type.
+// Implementations reuse the `json` struct tags of the api types so every
+// encoding shares the same field names.
+type Codec interface {
+	MediaType() string
+	Decode(r io.Reader, v any) error
+	Encode(w io.Writer, v any) error
+}
+
+type registration struct {
+	codec      Codec
+	mediaTypes []string
+}
+
+type registry struct {
+	mu      sync.RWMutex
+	entries []registration
+	byType  map[string]Codec
+}
+
+var codecs = &registry{byType: map[string]Codec{}}
+
+func init() {
+	Register(jsonCodec{})
+	Register(msgpackCodec{}, "application/x-msgpack", "application/vnd.msgpack")
+	Register(cborCodec{})
+}
+
+// Register makes codec available for decoding requests and negotiating
+// responses under its media type and any aliases. Codecs registered
+// earlier win ties during negotiation, so JSON stays the default.
+func Register(codec Codec, aliases ...string) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+
+	entry := registration{codec: codec}
+	for _, mediaType := range append([]string{codec.MediaType()}, aliases...) {
+		mediaType = strings.ToLower(mediaType)
+		entry.mediaTypes = append(entry.mediaTypes, mediaType)
+		codecs.byType[mediaType] = codec
+	}
+	codecs.entries = append(codecs.entries, entry)
+}
+
+// Lookup returns the codec registered for mediaType, without parameters.
+func Lookup(mediaType string) (Codec, bool) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	codec, ok := codecs.byType[strings.ToLower(mediaType)]
+	return codec, ok
+}
+
+// Default returns the codec used when the client expresses no preference.
+func Default() Codec {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	return codecs.entries[0].codec
+}
+
+func registered() []registration {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	return append([]registration(nil), codecs.entries...)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MediaType() string { return JSONContentType }
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	if err := decoder.Decode(v); err != nil {
+		return jsonError(err, decoder.InputOffset())
+	}
+	return nil
+}
+
+func (jsonCodec) Encode(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) MediaType() string { return MsgPackContentType }
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	decoder := msgpack.NewDecoder(r)
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
+
+func (msgpackCodec) DecodeStrict(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return bodyError(err)
+	}
+
+	var value any
+	if err = msgpack.Unmarshal(data, &value); err != nil {
+		return invalidBody("%v", err)
+	}
+	if field := unknownField(value, newFieldSet(reflect.TypeOf(v), false), ""); field != "" {
+		return invalidBody("unknown field %q", field)
+	}
+
+	reader := bytes.NewReader(data)
+	decoder := msgpack.NewDecoder(reader)
+	decoder.SetCustomStructTag("json")
+	decoder.DisallowUnknownFields(true)
+	if err = decoder.Decode(v); err != nil {
+		return invalidBody("%v", err)
+	}
+	if reader.Len() > 0 {
+		return invalidBody("unexpected data after MessagePack value at byte offset %d", len(data)-reader.Len())
+	}
+	return nil
+}
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+	return encoder.Encode(v)
+}
+
+var strictCBOR, _ = cbor.DecOptions{
+	DupMapKey:         cbor.DupMapKeyEnforcedAPF,
+	ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+}.DecMode()
+
+type cborCodec struct{}
+
+func (cborCodec) MediaType() string { return CBORContentType }
+
+func (cborCodec) Decode(r io.Reader, v any) error { return cbor.NewDecoder(r).Decode(v) }
+
+func (cborCodec) DecodeStrict(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return bodyError(err)
+	}
+	var value any
+	if err = strictCBOR.Unmarshal(data, &value); err != nil {
+		return invalidBody("%v", err)
+	}
+	if field := unknownField(value, newFieldSet(reflect.TypeOf(v), true), ""); field != "" {
+		return invalidBody("unknown field %q", field)
+	}
+	if err = strictCBOR.Unmarshal(data, v); err != nil {
+		return invalidBody("%v", err)
+	}
+	return nil
+}
+
+func (cborCodec) Encode(w io.Writer, v any) error { return cbor.NewEncoder(w).Encode(v) }
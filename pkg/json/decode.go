@@ -0,0 +1,262 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/giortzisg/go-boilerplate/pkg/compress"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+)
+
+// DefaultMaxBodySize bounds request bodies unless WithMaxBodySize says otherwise.
+const DefaultMaxBodySize int64 = 1 << 20
+
+var (
+	ErrBodyTooLarge        = errors.New("request body too large")
+	ErrInvalidBody         = errors.New("invalid request body")
+	ErrUnsupportedEncoding = compress.ErrUnsupportedEncoding
+)
+
+type decodeOptions struct {
+	maxBodySize int64
+	strict      bool
+}
+
+type DecodeOption func(o *decodeOptions)
+
+// WithMaxBodySize limits how many bytes are read from a request body before
+// answering 413. The limit applies both to the body as sent and, for
+// compressed bodies, to its decompressed content. A size of zero or less
+// removes the limit.
+func WithMaxBodySize(size int64) DecodeOption {
+	return func(o *decodeOptions) {
+		o.maxBodySize = size
+	}
+}
+
+// WithStrict rejects unknown fields, duplicate keys and anything following
+// the first value, for codecs that implement StrictCodec.
+func WithStrict(strict bool) DecodeOption {
+	return func(o *decodeOptions) {
+		o.strict = strict
+	}
+}
+
+func newDecodeOptions(opts []DecodeOption) decodeOptions {
+	o := decodeOptions{maxBodySize: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// StrictCodec is implemented by codecs able to validate a body beyond what
+// their lenient Decode accepts.
+type StrictCodec interface {
+	Codec
+	DecodeStrict(r io.Reader, v any) error
+}
+
+// ReadAll reads the whole request body, honoring the configured size limit.
+func ReadAll(r *http.Request, opts ...DecodeOption) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := requestBody(r, newDecodeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, bodyError(err)
+	}
+	return data, nil
+}
+
+// requestBody returns the request body with its Content-Encoding undone,
+// answering 415 for codings that are not supported.
+func requestBody(r *http.Request, o decodeOptions) (io.ReadCloser, error) {
+	body := limitReader(r.Body, o)
+	contentEncoding := r.Header.Get("Content-Encoding")
+	if contentEncoding == "" {
+		return body, nil
+	}
+
+	decoded, err := compress.NewReader(contentEncoding, body)
+	if errors.Is(err, compress.ErrUnsupportedEncoding) {
+		return nil, e.NewStatusError(err, http.StatusUnsupportedMediaType)
+	}
+	if err != nil {
+		return nil, bodyError(err)
+	}
+	// the decompressed size is capped too, so small bodies cannot inflate
+	// into something that exhausts memory
+	return limitReader(decoded, o), nil
+}
+
+func limitReader(body io.ReadCloser, o decodeOptions) io.ReadCloser {
+	if o.maxBodySize <= 0 {
+		return body
+	}
+	return http.MaxBytesReader(nil, body, o.maxBodySize)
+}
+
+// bodyError turns a decoding failure into a StatusError the ErrorHandler can
+// report: 413 for oversized bodies and 400 for everything else.
+func bodyError(err error) error {
+	var statusErr *e.StatusError
+	if errors.As(err, &statusErr) {
+		return err
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return e.NewStatusError(fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+	}
+
+	return invalidBody("%v", err)
+}
+
+func invalidBody(format string, args ...any) error {
+	return e.NewStatusError(fmt.Errorf("%w: "+format, append([]any{ErrInvalidBody}, args...)...), http.StatusBadRequest)
+}
+
+// jsonError describes a json decoding error in terms of the offending field
+// and byte offset. offset is used when err does not carry one itself.
+func jsonError(err error, offset int64) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		return bodyError(err)
+	case errors.Is(err, io.EOF):
+		return invalidBody("body must not be empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidBody("unexpected end of JSON at byte offset %d", offset)
+	case errors.As(err, &syntaxErr):
+		return invalidBody("malformed JSON at byte offset %d: %v", syntaxErr.Offset, syntaxErr)
+	case errors.As(err, &typeErr):
+		return invalidBody("field %q at byte offset %d must be %s, got %s", typeErr.Field, typeErr.Offset, typeErr.Type, typeErr.Value)
+	}
+	return invalidBody("%v", err)
+}
+
+// decodeStrictJSON decodes exactly one JSON value from r, rejecting unknown
+// fields, duplicate object keys and trailing data.
+func decodeStrictJSON(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return bodyError(err)
+	}
+
+	if err = checkFields(data, newFieldSet(reflect.TypeOf(v), true)); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(v); err != nil {
+		return jsonError(err, decoder.InputOffset())
+	}
+
+	offset := decoder.InputOffset()
+	if _, err = decoder.Token(); !errors.Is(err, io.EOF) {
+		return invalidBody("unexpected data after JSON value at byte offset %d", offset)
+	}
+	return nil
+}
+
+// checkFields walks the token stream of data and fails on the first object
+// that repeats a key or has a key fields does not know. Syntax errors are
+// left for the real decode.
+func checkFields(data []byte, fields *fieldSet) error {
+	type frame struct {
+		object    bool
+		expectKey bool
+		keys      map[string]struct{}
+		path      string
+		fields    *fieldSet
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var stack []*frame
+	path := func(key string) string {
+		if len(stack) == 0 || stack[len(stack)-1].path == "" {
+			return key
+		}
+		return stack[len(stack)-1].path + "." + key
+	}
+	// afterValue flips an enclosing object back to expecting a key
+	afterValue := func() {
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	var (
+		pending       string
+		pendingFields = fields
+	)
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				childPath, childFields := pending, pendingFields
+				if len(stack) > 0 && !stack[len(stack)-1].object {
+					childPath = stack[len(stack)-1].path + "[]"
+					childFields = stack[len(stack)-1].fields.elem()
+				}
+				stack = append(stack, &frame{
+					object:    t == '{',
+					expectKey: t == '{',
+					keys:      map[string]struct{}{},
+					path:      childPath,
+					fields:    childFields,
+				})
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				afterValue()
+			}
+		case string:
+			if top := len(stack) - 1; top >= 0 && stack[top].object && stack[top].expectKey {
+				// InputOffset still points before the separating comma
+				for offset < int64(len(data)) && bytes.IndexByte([]byte(", \t\r\n"), data[offset]) >= 0 {
+					offset++
+				}
+				if _, ok := stack[top].keys[t]; ok {
+					return invalidBody("duplicate field %q at byte offset %d", path(t), offset)
+				}
+				child, ok := stack[top].fields.field(t)
+				if !ok {
+					return invalidBody("unknown field %q at byte offset %d", path(t), offset)
+				}
+				stack[top].keys[t] = struct{}{}
+				stack[top].expectKey = false
+				pending, pendingFields = path(t), child
+				continue
+			}
+			afterValue()
+		default:
+			afterValue()
+		}
+	}
+}
+
+func (jsonCodec) DecodeStrict(r io.Reader, v any) error { return decodeStrictJSON(r, v) }
@@ -0,0 +1,167 @@
+package json
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// fieldSet is the shape a body is decoded into, used by the strict
+// decoders to name unknown fields without parsing decoder error text. A nil
+// fieldSet accepts anything, for types that decode themselves or hold any
+// value.
+type fieldSet struct {
+	typ reflect.Type
+	// foldCase also matches keys that differ from a field name only in
+	// case, as encoding/json and cbor do but msgpack does not
+	foldCase bool
+}
+
+var customDecoders = []reflect.Type{
+	reflect.TypeFor[json.Unmarshaler](),
+	reflect.TypeFor[encoding.TextUnmarshaler](),
+	reflect.TypeFor[encoding.BinaryUnmarshaler](),
+	reflect.TypeFor[msgpack.CustomDecoder](),
+	reflect.TypeFor[msgpack.Unmarshaler](),
+	reflect.TypeFor[cbor.Unmarshaler](),
+}
+
+// newFieldSet returns the fieldSet of values of typ, or nil when there is
+// nothing to check.
+func newFieldSet(typ reflect.Type, foldCase bool) *fieldSet {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() == reflect.Interface {
+		return nil
+	}
+	for _, decoder := range customDecoders {
+		if reflect.PointerTo(typ).Implements(decoder) {
+			return nil
+		}
+	}
+	return &fieldSet{typ: typ, foldCase: foldCase}
+}
+
+// field returns the fieldSet of the value stored under key, and false when
+// the type has no field for key.
+func (f *fieldSet) field(key string) (*fieldSet, bool) {
+	if f == nil {
+		return nil, true
+	}
+	switch f.typ.Kind() {
+	case reflect.Map:
+		return newFieldSet(f.typ.Elem(), f.foldCase), true
+	case reflect.Struct:
+		var folded reflect.Type
+		for name, typ := range structFields(f.typ) {
+			if name == key {
+				return newFieldSet(typ, f.foldCase), true
+			}
+			if f.foldCase && folded == nil && strings.EqualFold(name, key) {
+				folded = typ
+			}
+		}
+		if folded != nil {
+			return newFieldSet(folded, f.foldCase), true
+		}
+		return nil, false
+	}
+	return nil, true
+}
+
+// elem returns the fieldSet of the elements of an array.
+func (f *fieldSet) elem() *fieldSet {
+	if f == nil {
+		return nil
+	}
+	switch f.typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return newFieldSet(f.typ.Elem(), f.foldCase)
+	}
+	return nil
+}
+
+// structFields maps the names typ is decoded from to their field types,
+// honoring `json` tags and promoting the fields of untagged embedded
+// structs.
+func structFields(typ reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			for promoted, typ := range structFields(fieldType) {
+				if _, ok := fields[promoted]; !ok {
+					fields[promoted] = typ
+				}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// unknownField walks a value decoded into any and returns the path of the
+// first key that fields has no field for, or "" when there is none.
+func unknownField(value any, fields *fieldSet, path string) string {
+	if fields == nil {
+		return ""
+	}
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	check := func(key string, value any) string {
+		child, ok := fields.field(key)
+		if !ok {
+			return join(key)
+		}
+		return unknownField(value, child, join(key))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if unknown := check(key, value); unknown != "" {
+				return unknown
+			}
+		}
+	case map[any]any:
+		for key, value := range v {
+			if unknown := check(fmt.Sprint(key), value); unknown != "" {
+				return unknown
+			}
+		}
+	case []any:
+		for _, value := range v {
+			if unknown := unknownField(value, fields.elem(), path+"[]"); unknown != "" {
+				return unknown
+			}
+		}
+	}
+	return ""
+}
@@ -1,35 +1,93 @@
 package json
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
 )
 
 var (
 	InvalidContentTypeError = "Invalid content type"
+	NotAcceptableError      = "Not acceptable"
+
+	ErrUnsupportedMediaType = errors.New(InvalidContentTypeError)
+	ErrNotAcceptable        = errors.New(NotAcceptableError)
 )
 
-func Decoder[RequestType any](r *http.Request) (*RequestType, error) {
-	if r.Header.Get("Content-Type") != "application/json" {
-		return nil, fmt.Errorf("%s: %s", InvalidContentTypeError, r.Header.Get("Content-Type"))
+// Decoder decodes the request body with the codec registered for its
+// Content-Type, answering 415 when the media type or Content-Encoding is
+// unknown, 413 when the body exceeds the size limit and 400 when it cannot
+// be decoded.
+func Decoder[RequestType any](r *http.Request, opts ...DecodeOption) (*RequestType, error) {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, e.NewStatusError(fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType), http.StatusUnsupportedMediaType)
+	}
+	codec, ok := Lookup(mediaType)
+	if !ok {
+		return nil, e.NewStatusError(fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType), http.StatusUnsupportedMediaType)
 	}
 
 	var input RequestType
 	if r.Body != nil {
-		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		options := newDecodeOptions(opts)
+		body, err := requestBody(r, options)
+		if err != nil {
 			return nil, err
 		}
+		defer body.Close()
+
+		strictCodec, isStrict := codec.(StrictCodec)
+		if options.strict && isStrict {
+			err = strictCodec.DecodeStrict(body, &input)
+		} else {
+			err = codec.Decode(body, &input)
+		}
+		if err != nil {
+			return nil, bodyError(err)
+		}
 	}
 
 	return &input, nil
 }
 
-func Encoder[ResponseType any](w http.ResponseWriter, data *ResponseType, statusCode int) error {
-	w.Header().Set("Content-Type", "application/json")
+// NegotiateRequest picks the codec for the response to r from its Accept
+// header, answering 406 when no registered codec is acceptable.
+func NegotiateRequest(r *http.Request) (Codec, error) {
+	codec, ok := Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		return nil, e.NewStatusError(fmt.Errorf("%w: %s", ErrNotAcceptable, r.Header.Get("Accept")), http.StatusNotAcceptable)
+	}
+	return codec, nil
+}
+
+// Encoder writes data with the codec stored in the request context by
+// NewContext, negotiating one from the Accept header when there is none.
+// Nothing is written when no codec is acceptable, so the caller can still
+// report the 406.
+func Encoder[ResponseType any](w http.ResponseWriter, r *http.Request, data *ResponseType, statusCode int) error {
+	codec, ok := FromContext(r.Context())
+	if !ok {
+		var err error
+		if codec, err = NegotiateRequest(r); err != nil {
+			return err
+		}
+		w.Header().Add("Vary", "Accept")
+	}
+
+	return Write(w, codec, data, statusCode)
+}
+
+// Write encodes data with codec regardless of what the client accepts.
+func Write[ResponseType any](w http.ResponseWriter, codec Codec, data *ResponseType, statusCode int) error {
+	w.Header().Set("Content-Type", codec.MediaType())
 	w.WriteHeader(statusCode)
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
+	if err := codec.Encode(w, data); err != nil {
 		return err
 	}
 
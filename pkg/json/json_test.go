@@ -0,0 +1,250 @@
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/giortzisg/go-boilerplate/pkg/compress"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type payload struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+		wantOk bool
+	}{
+		{name: "empty accept defaults to json", accept: "", want: JSONContentType, wantOk: true},
+		{name: "wildcard defaults to json", accept: "*/*", want: JSONContentType, wantOk: true},
+		{name: "exact match", accept: "application/cbor", want: CBORContentType, wantOk: true},
+		{name: "alias match", accept: "application/x-msgpack", want: MsgPackContentType, wantOk: true},
+		{name: "highest quality wins", accept: "application/json;q=0.5, application/msgpack", want: MsgPackContentType, wantOk: true},
+		{name: "specific range overrides wildcard", accept: "application/*, application/json;q=0", want: MsgPackContentType, wantOk: true},
+		{name: "nothing acceptable", accept: "text/html", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, ok := Negotiate(tt.accept)
+			if ok != tt.wantOk {
+				t.Fatalf("Negotiate(%q) ok = %v, want %v", tt.accept, ok, tt.wantOk)
+			}
+			if ok && codec.MediaType() != tt.want {
+				t.Errorf("Negotiate(%q) = %s, want %s", tt.accept, codec.MediaType(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := &payload{Name: "Test User", Email: "test@example.com"}
+
+	for _, mediaType := range []string{JSONContentType, MsgPackContentType, CBORContentType} {
+		t.Run(mediaType, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", mediaType)
+			if err := Encoder(w, r, in, http.StatusOK); err != nil {
+				t.Fatalf("Encoder() error = %v", err)
+			}
+			if got := w.Header().Get("Content-Type"); got != mediaType {
+				t.Fatalf("Content-Type = %s, want %s", got, mediaType)
+			}
+
+			r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(w.Body.Bytes()))
+			r.Header.Set("Content-Type", mediaType+"; charset=utf-8")
+			out, err := Decoder[payload](r)
+			if err != nil {
+				t.Fatalf("Decoder() error = %v", err)
+			}
+			if *out != *in {
+				t.Errorf("round trip = %+v, want %+v", out, in)
+			}
+		})
+	}
+}
+
+func TestDecoder_UnsupportedMediaType(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("name=x")))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	_, err := Decoder[payload](r)
+	var statusErr interface{ HTTPStatus() int }
+	if !errors.As(err, &statusErr) || statusErr.HTTPStatus() != http.StatusUnsupportedMediaType {
+		t.Errorf("Decoder() error = %v, want 415", err)
+	}
+}
+
+func TestEncoder_NotAcceptable(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html")
+
+	err := Encoder(w, r, &payload{}, http.StatusOK)
+	if !errors.Is(err, ErrNotAcceptable) {
+		t.Errorf("Encoder() error = %v, want %v", err, ErrNotAcceptable)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Encoder() wrote %d bytes, want none", w.Body.Len())
+	}
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		opts       []DecodeOption
+		wantStatus int
+		wantMsg    string
+	}{
+		{name: "body too large", body: `{"name":"Test User"}`, opts: []DecodeOption{WithMaxBodySize(8)}, wantStatus: http.StatusRequestEntityTooLarge, wantMsg: "limit is 8 bytes"},
+		{name: "wrong field type", body: `{"name":1}`, wantStatus: http.StatusBadRequest, wantMsg: `field "name" at byte offset 9 must be string`},
+		{name: "syntax error", body: `{"name":}`, wantStatus: http.StatusBadRequest, wantMsg: "malformed JSON at byte offset 9"},
+		{name: "unknown fields are ignored by default", body: `{"name":"x","age":1}`},
+		{name: "strict unknown field", body: `{"name":"x","age":1}`, opts: []DecodeOption{WithStrict(true)}, wantStatus: http.StatusBadRequest, wantMsg: `unknown field "age"`},
+		{name: "strict duplicate key", body: `{"name":"x","name":"y"}`, opts: []DecodeOption{WithStrict(true)}, wantStatus: http.StatusBadRequest, wantMsg: `duplicate field "name" at byte offset 12`},
+		{name: "strict trailing value", body: `{"name":"x"} {}`, opts: []DecodeOption{WithStrict(true)}, wantStatus: http.StatusBadRequest, wantMsg: "unexpected data after JSON value at byte offset 12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(tt.body)))
+			r.Header.Set("Content-Type", JSONContentType)
+
+			_, err := Decoder[payload](r, tt.opts...)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("Decoder() error = %v", err)
+				}
+				return
+			}
+
+			var statusErr interface {
+				error
+				HTTPStatus() int
+			}
+			if !errors.As(err, &statusErr) || statusErr.HTTPStatus() != tt.wantStatus {
+				t.Fatalf("Decoder() error = %v, want status %d", err, tt.wantStatus)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Decoder() error = %q, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestDecoder_StrictUnknownField(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type customer struct {
+		Name      string    `json:"name"`
+		Address   address   `json:"address"`
+		Addresses []address `json:"addresses"`
+	}
+
+	tests := []struct {
+		name    string
+		body    map[string]any
+		wantMsg string
+	}{
+		{name: "known fields", body: map[string]any{"name": "x", "address": map[string]any{"city": "y"}}},
+		{name: "top level", body: map[string]any{"name": "x", "age": 1}, wantMsg: `unknown field "age"`},
+		{name: "nested object", body: map[string]any{"address": map[string]any{"city": "y", "zip": "1"}}, wantMsg: `unknown field "address.zip"`},
+		{name: "object in array", body: map[string]any{"addresses": []any{map[string]any{"zip": "1"}}}, wantMsg: `unknown field "addresses[].zip"`},
+	}
+	encoders := map[string]func(v any) ([]byte, error){
+		JSONContentType:    stdjson.Marshal,
+		MsgPackContentType: msgpack.Marshal,
+		CBORContentType:    cbor.Marshal,
+	}
+	for mediaType, encode := range encoders {
+		for _, tt := range tests {
+			t.Run(mediaType+"/"+tt.name, func(t *testing.T) {
+				body, err := encode(tt.body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+				r.Header.Set("Content-Type", mediaType)
+
+				_, err = Decoder[customer](r, WithStrict(true))
+				if tt.wantMsg == "" {
+					if err != nil {
+						t.Fatalf("Decoder() error = %v", err)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Errorf("Decoder() error = %v, want it to contain %q", err, tt.wantMsg)
+				}
+			})
+		}
+	}
+}
+
+func TestDecoder_ContentEncoding(t *testing.T) {
+	compressed := func(name, body string) []byte {
+		encoding, _ := compress.Lookup(name)
+		var buf bytes.Buffer
+		w := encoding.NewWriter(&buf)
+		_, _ = w.Write([]byte(body))
+		_ = w.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		opts            []DecodeOption
+		wantStatus      int
+	}{
+		{name: "gzip", contentEncoding: "gzip", body: compressed(compress.Gzip, `{"name":"Test User"}`)},
+		{name: "zstd", contentEncoding: "zstd", body: compressed(compress.Zstd, `{"name":"Test User"}`)},
+		{name: "brotli", contentEncoding: "br", body: compressed(compress.Brotli, `{"name":"Test User"}`)},
+		{name: "deflate", contentEncoding: "deflate", body: compressed(compress.Deflate, `{"name":"Test User"}`)},
+		{name: "unsupported encoding", contentEncoding: "compress", body: []byte(`{}`), wantStatus: http.StatusUnsupportedMediaType},
+		{name: "corrupt body", contentEncoding: "gzip", body: []byte(`{"name":"Test User"}`), wantStatus: http.StatusBadRequest},
+		{
+			name:            "decompressed size is limited",
+			contentEncoding: "gzip",
+			body:            compressed(compress.Gzip, `{"name":"`+strings.Repeat("a", 4096)+`"}`),
+			opts:            []DecodeOption{WithMaxBodySize(1024)},
+			wantStatus:      http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			r.Header.Set("Content-Type", JSONContentType)
+			r.Header.Set("Content-Encoding", tt.contentEncoding)
+
+			out, err := Decoder[payload](r, tt.opts...)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("Decoder() error = %v", err)
+				}
+				if out.Name != "Test User" {
+					t.Errorf("Decoder() = %+v", out)
+				}
+				return
+			}
+
+			var statusErr interface{ HTTPStatus() int }
+			if !errors.As(err, &statusErr) || statusErr.HTTPStatus() != tt.wantStatus {
+				t.Errorf("Decoder() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
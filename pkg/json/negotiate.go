@@ -0,0 +1,97 @@
+package json
+
+import (
+	"context"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// specificity ranks how closely r matches mediaType: 0 means no match,
+// 1 a */* range, 2 a type/* range and 3 an exact match.
+func (r mediaRange) specificity(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case r.typ == "*" && r.subtype == "*":
+		return 1
+	case r.typ == typ && r.subtype == "*":
+		return 2
+	case r.typ == typ && r.subtype == subtype:
+		return 3
+	}
+	return 0
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// Negotiate picks the registered codec the client prefers according to an
+// Accept header value. An empty header accepts anything, which selects the
+// first registered codec. ok is false when nothing acceptable is registered.
+func Negotiate(accept string) (codec Codec, ok bool) {
+	available := registered()
+	if strings.TrimSpace(accept) == "" {
+		return available[0].codec, true
+	}
+
+	ranges := parseAccept(accept)
+	best := 0.0
+	for _, candidate := range available {
+		// the most specific matching range decides the quality of a type
+		specificity, q := 0, 0.0
+		for _, mediaType := range candidate.mediaTypes {
+			for _, r := range ranges {
+				if s := r.specificity(mediaType); s > specificity {
+					specificity, q = s, r.q
+				}
+			}
+		}
+		if q > best {
+			codec, best = candidate.codec, q
+		}
+	}
+	return codec, codec != nil
+}
+
+type codecKey struct{}
+
+// NewContext returns a copy of ctx carrying the codec negotiated for the
+// response, so that it is chosen before the handler has any side effect.
+func NewContext(ctx context.Context, codec Codec) context.Context {
+	return context.WithValue(ctx, codecKey{}, codec)
+}
+
+// FromContext returns the codec stored by NewContext.
+func FromContext(ctx context.Context) (Codec, bool) {
+	codec, ok := ctx.Value(codecKey{}).(Codec)
+	return codec, ok
+}
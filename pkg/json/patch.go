@@ -0,0 +1,72 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	e "github.com/giortzisg/go-boilerplate/pkg/error"
+)
+
+const (
+	// MergePatchContentType identifies an RFC 7396 JSON Merge Patch document.
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType identifies an RFC 6902 JSON Patch document.
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+var (
+	ErrUnsupportedPatch = errors.New("unsupported patch content type")
+	ErrInvalidPatch     = errors.New("invalid patch document")
+	ErrPatchFailed      = errors.New("patch could not be applied")
+)
+
+// Patch applies a merge patch or JSON patch, selected by contentType, to the
+// JSON representation of current and decodes the outcome into a new value.
+// Fields that do not exist on RequestType are rejected so a patch cannot
+// smuggle in attributes the representation does not expose.
+func Patch[RequestType any](contentType string, current *RequestType, patch []byte) (*RequestType, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, e.NewStatusError(fmt.Errorf("%w: %s", ErrUnsupportedPatch, contentType), http.StatusUnsupportedMediaType)
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch mediaType {
+	case MergePatchContentType:
+		if !json.Valid(patch) {
+			return nil, e.NewStatusError(ErrInvalidPatch, http.StatusBadRequest)
+		}
+		if patched, err = jsonpatch.MergePatch(doc, patch); err != nil {
+			return nil, e.NewStatusError(fmt.Errorf("%w: %v", ErrInvalidPatch, err), http.StatusBadRequest)
+		}
+	case JSONPatchContentType:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, e.NewStatusError(fmt.Errorf("%w: %v", ErrInvalidPatch, err), http.StatusBadRequest)
+		}
+		if patched, err = ops.Apply(doc); err != nil {
+			return nil, e.NewStatusError(fmt.Errorf("%w: %v", ErrPatchFailed, err), http.StatusUnprocessableEntity)
+		}
+	default:
+		return nil, e.NewStatusError(fmt.Errorf("%w: %s", ErrUnsupportedPatch, mediaType), http.StatusUnsupportedMediaType)
+	}
+
+	var result RequestType
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&result); err != nil {
+		return nil, e.NewStatusError(fmt.Errorf("%w: %v", ErrPatchFailed, err), http.StatusUnprocessableEntity)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,248 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Formats of the log output.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+	// FormatPretty is colored, human friendly output for local development.
+	FormatPretty = "pretty"
+)
+
+// Config describes how to log. Output is "stdout", "stderr" or the path of
+// a file to append to, and defaults to stdout. Packages sets the level of
+// the named loggers, overriding Level.
+type Config struct {
+	Level     string
+	Format    string
+	Output    string
+	AddSource bool
+	Packages  map[string]string
+}
+
+type Option func(l *Loggers)
+
+// WithHandler wraps the handler every logger writes through, such as
+// correlation.NewHandler.
+func WithHandler(wrap func(slog.Handler) slog.Handler) Option {
+	return func(l *Loggers) {
+		l.wraps = append(l.wraps, wrap)
+	}
+}
+
+// Loggers hands out the root logger and loggers named after the package
+// using them, and lets their levels change at runtime.
+type Loggers struct {
+	handler slog.Handler
+	writer  io.Writer
+	output  io.Closer
+	wraps   []func(slog.Handler) slog.Handler
+
+	root     slog.LevelVar
+	mu       sync.RWMutex
+	packages map[string]*slog.LevelVar
+}
+
+// New sets up logging as described by conf.
+func New(conf Config, opts ...Option) (*Loggers, error) {
+	l := &Loggers{packages: map[string]*slog.LevelVar{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if err := l.Configure(conf); err != nil {
+		return nil, err
+	}
+
+	var w io.Writer
+	switch conf.Output {
+	case "", "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		f, err := os.OpenFile(conf.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening log output: %w", err)
+		}
+		w, l.output = f, f
+	}
+	l.writer = w
+
+	// levels are checked by levelHandler, so the handler lets everything pass
+	handlerOptions := &slog.HandlerOptions{AddSource: conf.AddSource, Level: slog.Level(-1 << 10)}
+	switch conf.Format {
+	case "", FormatJSON:
+		l.handler = slog.NewJSONHandler(w, handlerOptions)
+	case FormatText:
+		l.handler = slog.NewTextHandler(w, handlerOptions)
+	case FormatPretty:
+		l.handler = NewPrettyHandler(w, handlerOptions)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", conf.Format)
+	}
+	for _, wrap := range l.wraps {
+		l.handler = wrap(l.handler)
+	}
+	return l, nil
+}
+
+// Configure applies the levels of conf. The format and output are fixed
+// once logging is set up.
+func (l *Loggers) Configure(conf Config) error {
+	level, err := ParseLevel(conf.Level)
+	if err != nil {
+		return err
+	}
+	packages := map[string]slog.Level{}
+	for name, value := range conf.Packages {
+		if packages[name], err = ParseLevel(value); err != nil {
+			return fmt.Errorf("package %s: %w", name, err)
+		}
+	}
+
+	l.root.Set(level)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for name := range l.packages {
+		if _, ok := packages[name]; !ok {
+			delete(l.packages, name)
+		}
+	}
+	for name, level := range packages {
+		l.levelVar(name).Set(level)
+	}
+	return nil
+}
+
+// ParseLevel reads a level name such as "debug" or an offset such as
+// "info+2". An empty string is info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s = strings.TrimSpace(s); s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return level, nil
+}
+
+// Logger returns the root logger.
+func (l *Loggers) Logger() *slog.Logger {
+	return slog.New(&levelHandler{Handler: l.handler, loggers: l})
+}
+
+// Named returns the logger of a package, which follows the root level
+// unless the package has a level of its own. Its records carry the name as
+// the "logger" attribute.
+func (l *Loggers) Named(name string) *slog.Logger {
+	return slog.New(&levelHandler{
+		Handler: l.handler.WithAttrs([]slog.Attr{slog.String("logger", name)}),
+		loggers: l,
+		name:    name,
+	})
+}
+
+// Unleveled returns a logger that writes every record whatever the levels
+// are set to, for the few events that must always be on record, such as a
+// change of those levels. Its records carry the name like those of Named.
+func (l *Loggers) Unleveled(name string) *slog.Logger {
+	return slog.New(l.handler.WithAttrs([]slog.Attr{slog.String("logger", name)}))
+}
+
+// Level returns the level of a package, or of the root logger when name is
+// empty.
+func (l *Loggers) Level(name string) slog.Level {
+	if name != "" {
+		l.mu.RLock()
+		v, ok := l.packages[name]
+		l.mu.RUnlock()
+		if ok {
+			return v.Level()
+		}
+	}
+	return l.root.Level()
+}
+
+// SetLevel changes the level of a package, or of the root logger when
+// name is empty.
+func (l *Loggers) SetLevel(name string, level slog.Level) {
+	if name == "" {
+		l.root.Set(level)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.levelVar(name).Set(level)
+}
+
+// ResetLevel makes a package follow the root level again.
+func (l *Loggers) ResetLevel(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.packages, name)
+}
+
+// Packages returns the levels of the packages that have one of their own.
+func (l *Loggers) Packages() map[string]slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	levels := make(map[string]slog.Level, len(l.packages))
+	for name, v := range l.packages {
+		levels[name] = v.Level()
+	}
+	return levels
+}
+
+// Writer returns the configured output, for logs written in a format of
+// their own such as combined access logs.
+func (l *Loggers) Writer() io.Writer {
+	return l.writer
+}
+
+// Close closes the log file, if logging to one.
+func (l *Loggers) Close() error {
+	if l.output == nil {
+		return nil
+	}
+	return l.output.Close()
+}
+
+// levelVar returns the LevelVar of a package, creating it. l.mu must be
+// held for writing.
+func (l *Loggers) levelVar(name string) *slog.LevelVar {
+	v, ok := l.packages[name]
+	if !ok {
+		v = new(slog.LevelVar)
+		l.packages[name] = v
+	}
+	return v
+}
+
+// levelHandler drops records below the current level of its logger.
+type levelHandler struct {
+	slog.Handler
+	loggers *Loggers
+	name    string
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.loggers.Level(h.name) && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), loggers: h.loggers, name: h.name}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), loggers: h.loggers, name: h.name}
+}
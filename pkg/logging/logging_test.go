@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggers_PackageLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	loggers, err := New(Config{
+		Level:    "info",
+		Format:   FormatJSON,
+		Output:   path,
+		Packages: map[string]string{"gorm": "warn", "handlers": "debug"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer loggers.Close()
+
+	root, gorm, handlers, other := loggers.Logger(), loggers.Named("gorm"), loggers.Named("handlers"), loggers.Named("other")
+	logAll := func() {
+		root.Debug("root debug")
+		root.Info("root info")
+		gorm.Info("gorm info")
+		gorm.Warn("gorm warn")
+		handlers.Debug("handlers debug")
+		other.Debug("other debug")
+		other.Info("other info")
+	}
+	logAll()
+
+	// packages follow changes to the root level unless they have their own
+	loggers.SetLevel("", slog.LevelDebug)
+	loggers.ResetLevel("gorm")
+	gorm.Info("gorm info after reset")
+	other.Debug("other debug after root change")
+
+	if err := loggers.Configure(Config{Level: "error"}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	handlers.Debug("handlers debug after reload")
+	root.Error("root error after reload")
+	loggers.Unleveled("admin").Info("unleveled info")
+
+	output, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	var got []string
+	for _, match := range regexp.MustCompile(`"msg":"([^"]+)"`).FindAllStringSubmatch(string(output), -1) {
+		got = append(got, match[1])
+	}
+	want := []string{"root info", "gorm warn", "handlers debug", "other info", "gorm info after reset", "other debug after root change", "root error after reload", "unleveled info"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("logged %v, want %v", got, want)
+	}
+	if !strings.Contains(string(output), `"logger":"gorm"`) {
+		t.Errorf("named logger records lack the logger attribute: %s", output)
+	}
+	if len(loggers.Packages()) != 0 {
+		t.Errorf("Packages() = %v after reload without packages, want none", loggers.Packages())
+	}
+}
+
+func TestLoggers_Writer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	loggers, err := New(Config{Output: path})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer loggers.Close()
+
+	loggers.Logger().Info("structured")
+	if _, err := loggers.Writer().Write([]byte("raw line\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	output, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	if !strings.Contains(string(output), `"msg":"structured"`) || !strings.HasSuffix(string(output), "raw line\n") {
+		t.Errorf("log = %q, want both entries in the configured output", output)
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	if _, err := New(Config{Level: "loud"}); err == nil {
+		t.Error("New() with an unknown level succeeded")
+	}
+	if _, err := New(Config{Format: "xml"}); err == nil {
+		t.Error("New() with an unknown format succeeded")
+	}
+	if _, err := New(Config{Packages: map[string]string{"gorm": "quiet"}}); err == nil {
+		t.Error("New() with an unknown package level succeeded")
+	}
+}
+
+func TestPrettyHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})).
+		With("logger", "handlers").
+		WithGroup("request")
+
+	r := slog.NewRecord(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), slog.LevelWarn, "slow request", 0)
+	r.AddAttrs(
+		slog.String("path", "/users"),
+		slog.String("agent", "curl 8.0"),
+		slog.Any("error", errors.New("boom")),
+		slog.Group("db", slog.Int("queries", 3)),
+	)
+	if err := logger.Handler().Handle(context.Background(), r); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	plain := regexp.MustCompile("\033\\[[0-9]+m").ReplaceAllString(buf.String(), "")
+	want := `15:04:05.000 WRN slow request logger=handlers request.path=/users request.agent="curl 8.0" request.error=boom request.db.queries=3` + "\n"
+	if plain != want {
+		t.Errorf("output = %q, want %q", plain, want)
+	}
+	if !strings.Contains(buf.String(), colorYellow+"WRN") {
+		t.Errorf("warning level is not colored: %q", buf.String())
+	}
+}
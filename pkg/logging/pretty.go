@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorDim    = "\033[2m"
+	colorBold   = "\033[1m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+)
+
+// PrettyHandler writes colored, single line records meant for a terminal:
+//
+//	15:04:05.000 INF message key=value group.key=value
+//
+// Only the Level and AddSource fields of the options are used.
+type PrettyHandler struct {
+	opts   slog.HandlerOptions
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	attrs  []byte
+}
+
+func NewPrettyHandler(w io.Writer, opts *slog.HandlerOptions) *PrettyHandler {
+	h := &PrettyHandler{mu: &sync.Mutex{}, w: w}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+	if !r.Time.IsZero() {
+		buf = append(buf, colorDim...)
+		buf = r.Time.AppendFormat(buf, "15:04:05.000")
+		buf = append(buf, colorReset+" "...)
+	}
+	buf = appendLevel(buf, r.Level)
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		buf = fmt.Appendf(buf, " %s%s:%d%s", colorDim, filepath.Base(frame.File), frame.Line, colorReset)
+	}
+	buf = append(buf, ' ')
+	buf = append(buf, colorBold...)
+	buf = append(buf, r.Message...)
+	buf = append(buf, colorReset...)
+	buf = append(buf, h.attrs...)
+	r.Attrs(func(attr slog.Attr) bool {
+		buf = appendAttr(buf, h.prefix, attr)
+		return true
+	})
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]byte(nil), h.attrs...)
+	for _, attr := range attrs {
+		h2.attrs = appendAttr(h2.attrs, h.prefix, attr)
+	}
+	return &h2
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+func appendLevel(buf []byte, level slog.Level) []byte {
+	color, name := colorRed, "ERR"
+	switch {
+	case level < slog.LevelInfo:
+		color, name = colorBlue, "DBG"
+	case level < slog.LevelWarn:
+		color, name = colorGreen, "INF"
+	case level < slog.LevelError:
+		color, name = colorYellow, "WRN"
+	}
+	buf = append(buf, color...)
+	buf = append(buf, name...)
+	return append(buf, colorReset...)
+}
+
+func appendAttr(buf []byte, prefix string, attr slog.Attr) []byte {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return buf
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			buf = appendAttr(buf, prefix, a)
+		}
+		return buf
+	}
+
+	buf = append(buf, ' ')
+	buf = append(buf, colorDim...)
+	buf = append(buf, prefix...)
+	buf = append(buf, attr.Key...)
+	buf = append(buf, '=')
+	buf = append(buf, colorReset...)
+
+	var value string
+	switch attr.Value.Kind() {
+	case slog.KindTime:
+		value = attr.Value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			buf = append(buf, colorRed...)
+			buf = appendString(buf, err.Error())
+			return append(buf, colorReset...)
+		}
+		value = fmt.Sprint(attr.Value.Any())
+	default:
+		value = attr.Value.String()
+	}
+	return appendString(buf, value)
+}
+
+// appendString quotes s when it would not read back as a single value.
+func appendString(buf []byte, s string) []byte {
+	if s == "" {
+		return append(buf, `""`...)
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return strconv.AppendQuote(buf, s)
+		}
+	}
+	return append(buf, s...)
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/giortzisg/go-boilerplate/internal/model"
 	"gorm.io/gorm"
 	"log/slog"
-	"os"
 )
 
 type MigrateServer struct {
@@ -22,12 +21,19 @@ func NewMigrateServer(db *gorm.DB, log *slog.Logger) *MigrateServer {
 func (m *MigrateServer) Start(ctx context.Context) error {
 	if err := m.db.AutoMigrate(
 		&model.User{},
+		&model.RateLimitBucket{},
+		&model.LoginAttempt{},
+		&model.IdempotencyRecord{},
+		&model.AuditRecord{},
+		&model.OutboxEvent{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.WebhookAttempt{},
 	); err != nil {
 		m.log.Warn("user migrate error", "err", err)
 		return err
 	}
 	m.log.Info("AutoMigrate success")
-	os.Exit(0)
 	return nil
 }
 func (m *MigrateServer) Stop(ctx context.Context) error {
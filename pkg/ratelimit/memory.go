@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+// MemoryStore keeps buckets in process memory. Limits are enforced per
+// replica, so use a shared store when running more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*memoryBucket{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{Bucket: limit.Full(now), limit: limit}
+		s.buckets[key] = b
+	}
+
+	var result Result
+	b.Bucket, result = limit.Take(b.Bucket, now)
+	b.limit = limit
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, since a missing bucket
+// behaves exactly like a full one.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if b.limit.Refilled(b.Bucket, now) {
+			delete(s.buckets, key)
+		}
+	}
+}
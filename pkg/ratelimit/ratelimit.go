@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Limit allows Requests per Period on average, with bursts of up to Burst
+// requests. A zero Burst means Requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func PerSecond(requests int) Limit {
+	return Limit{Requests: requests, Period: time.Second}
+}
+
+func PerMinute(requests int) Limit {
+	return Limit{Requests: requests, Period: time.Minute}
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate is the refill speed in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) Valid() bool {
+	return l.Requests > 0 && l.Period > 0 && l.Burst >= 0
+}
+
+// Policy renders the limit for the RateLimit-Policy header.
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", int(l.capacity()), int(math.Ceil(l.Period.Seconds())))
+}
+
+// Bucket is the persisted state of a token bucket.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Full returns a bucket holding the whole burst, as seen by a new client.
+func (l Limit) Full(now time.Time) Bucket {
+	return Bucket{Tokens: l.capacity(), UpdatedAt: now}
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed. It is
+	// zero for allowed requests.
+	RetryAfter time.Duration
+}
+
+// Take refills b for the time elapsed since it was last updated and then
+// tries to remove one token. Stores persist the returned bucket.
+func (l Limit) Take(b Bucket, now time.Time) (Bucket, Result) {
+	capacity, rate := l.capacity(), l.rate()
+	tokens := l.tokens(b, now)
+
+	result := Result{Limit: int(capacity)}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((capacity - tokens) / rate)
+
+	return Bucket{Tokens: tokens, UpdatedAt: now}, result
+}
+
+// tokens returns what b holds at now after refilling.
+func (l Limit) tokens(b Bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.UpdatedAt).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(l.capacity(), b.Tokens+elapsed*l.rate())
+}
+
+// Refilled reports whether b is full again at now, meaning it can be
+// forgotten without changing the outcome of later requests.
+func (l Limit) Refilled(b Bucket, now time.Time) bool {
+	return l.tokens(b, now) >= l.capacity()
+}
+
+// RefilledAt returns when b is full again if no tokens are taken from it.
+func (l Limit) RefilledAt(b Bucket) time.Time {
+	missing := math.Max(0, l.capacity()-b.Tokens)
+	return b.UpdatedAt.Add(seconds(missing / l.rate()))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps token buckets, keyed by client and route. Implementations
+// must apply Take atomically per key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
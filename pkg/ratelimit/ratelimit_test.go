@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimit_Take(t *testing.T) {
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 3}
+	now := time.Now()
+	bucket := limit.Full(now)
+
+	var result Result
+	for i := 0; i < 3; i++ {
+		bucket, result = limit.Take(bucket, now)
+		if !result.Allowed {
+			t.Fatalf("expected request %d within the burst to be allowed", i+1)
+		}
+	}
+	if result.Remaining != 0 || result.Limit != 3 {
+		t.Errorf("expected 0 of 3 remaining, got %d of %d", result.Remaining, result.Limit)
+	}
+
+	bucket, result = limit.Take(bucket, now)
+	if result.Allowed {
+		t.Fatal("expected request beyond the burst to be rejected")
+	}
+	if result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("expected to retry after 500ms, got %v", result.RetryAfter)
+	}
+
+	_, result = limit.Take(bucket, now.Add(500*time.Millisecond))
+	if !result.Allowed {
+		t.Error("expected request to be allowed after refilling one token")
+	}
+}
+
+func TestLimit_RefilledAt(t *testing.T) {
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 3}
+	now := time.Now()
+
+	bucket := Bucket{Tokens: 1, UpdatedAt: now}
+	refilled := limit.RefilledAt(bucket)
+	if want := now.Add(time.Second); !refilled.Equal(want) {
+		t.Errorf("expected the bucket to refill at %v, got %v", want, refilled)
+	}
+	if limit.Refilled(bucket, refilled.Add(-time.Millisecond)) || !limit.Refilled(bucket, refilled) {
+		t.Error("expected RefilledAt to agree with Refilled")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	limit := PerMinute(1)
+
+	if result, _ := store.Take(context.Background(), "a", limit); !result.Allowed {
+		t.Fatal("expected first request to be allowed")
+	}
+	if result, _ := store.Take(context.Background(), "a", limit); result.Allowed {
+		t.Fatal("expected second request to be rejected")
+	}
+	if result, _ := store.Take(context.Background(), "b", limit); !result.Allowed {
+		t.Fatal("expected other keys to have their own bucket")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if result, _ := store.Take(context.Background(), "a", limit); !result.Allowed {
+		t.Fatal("expected request to be allowed once the bucket refilled")
+	}
+	if _, ok := store.buckets["b"]; ok {
+		t.Error("expected refilled buckets to be swept")
+	}
+}
@@ -3,12 +3,15 @@ package http
 import (
 	"context"
 	"errors"
-	"fmt"
 	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
+	"sync"
 	"time"
 )
 
@@ -18,14 +21,55 @@ type Server struct {
 	host   string
 	port   int
 	logger *slog.Logger
+
+	tracerProvider trace.TracerProvider
+	tls            *TLSConfig
+
+	onShutdown      []func()
+	shutdownTimeout time.Duration
+	shutdownDelay   time.Duration
+	// runMu guards started and stopped, so that Stop either waits for a
+	// running Start or keeps a later one from serving at all
+	runMu   sync.Mutex
+	started bool
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+
+	listener   net.Listener
+	socketPath string
+	socketMode os.FileMode
+	h2c        bool
+	ready      chan struct{}
+	readyOnce  sync.Once
+	addr       net.Addr
+
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
 }
 
+// Defaults applied to every Server. Read and write timeouts stay unset by
+// default since they would cut off slow uploads and long responses; request
+// deadlines are better handled per route.
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultIdleTimeout       = 2 * time.Minute
+)
+
 type Option func(s *Server)
 
 func NewServer(mux *chi.Mux, logger *slog.Logger, args ...Option) *Server {
 	s := &Server{
-		Mux:    mux,
-		logger: logger,
+		Mux:               mux,
+		logger:            logger,
+		readHeaderTimeout: DefaultReadHeaderTimeout,
+		idleTimeout:       DefaultIdleTimeout,
+		shutdownTimeout:   DefaultShutdownTimeout,
+		ready:             make(chan struct{}),
+		stop:              make(chan struct{}),
+		done:              make(chan struct{}),
 	}
 
 	for _, opts := range args {
@@ -45,19 +89,105 @@ func WithPort(port int) Option {
 	}
 }
 
-func (s *Server) Start(callerCtx context.Context) error {
-	ctx, stop := signal.NotifyContext(callerCtx, os.Interrupt)
-	defer stop()
+// WithReadHeaderTimeout bounds how long a client may take to send request
+// headers, which keeps slowloris style clients from tying up connections.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.readHeaderTimeout = d
+	}
+}
+
+// WithReadTimeout bounds how long reading a whole request may take.
+func WithReadTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.readTimeout = d
+	}
+}
+
+// WithWriteTimeout bounds how long writing a response may take, counted
+// from the end of the request headers.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.writeTimeout = d
+	}
+}
+
+// WithIdleTimeout bounds how long a keep-alive connection may sit idle.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.idleTimeout = d
+	}
+}
+
+// handler returns the mux wrapped in the instrumentation enabled by options.
+func (s *Server) handler() http.Handler {
+	var h http.Handler = s.Mux
+	if s.tls != nil {
+		h = withClientIdentity(h)
+	}
+	if s.tracerProvider != nil {
+		h = s.traced(h)
+	}
+	if s.h2c && s.tls == nil {
+		h = h2c.NewHandler(h, &http2.Server{IdleTimeout: s.idleTimeout})
+	}
+	return h
+}
+
+// WithOnShutdown registers fn to run as soon as graceful shutdown begins,
+// before the listener stops accepting connections.
+func WithOnShutdown(fn func()) Option {
+	return func(s *Server) {
+		s.onShutdown = append(s.onShutdown, fn)
+	}
+}
+
+// Start serves until ctx is done or Stop is called, then shuts down
+// gracefully. Signals are left to the caller, such as server.Runner, so
+// that one signal handler owns them for the whole process.
+// A Server that was stopped before it started does not serve at all.
+func (s *Server) Start(ctx context.Context) error {
+	// waiters are released even when the server never listens
+	defer s.markReady()
+	if !s.begin() {
+		return nil
+	}
+	defer close(s.done)
+
+	l, err := s.listen()
+	if err != nil {
+		s.logger.Error("Error starting the HTTP server", "error", err)
+		return err
+	}
 
 	s.srv = &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", s.host, s.port),
-		Handler: s,
+		Addr:              l.Addr().String(),
+		Handler:           s.handler(),
+		ReadHeaderTimeout: s.readHeaderTimeout,
+		ReadTimeout:       s.readTimeout,
+		WriteTimeout:      s.writeTimeout,
+		IdleTimeout:       s.idleTimeout,
 	}
 
+	serve := func() error { return s.srv.Serve(l) }
+	if s.tls != nil {
+		reloader, err := newCertReloader(*s.tls, s.logger)
+		if err != nil {
+			_ = l.Close()
+			s.logger.Error("Error loading TLS files", "error", err)
+			return err
+		}
+		s.srv.TLSConfig = reloader.tlsConfig()
+		serve = func() error { return s.srv.ServeTLS(l, "", "") }
+	}
+
+	s.addr = l.Addr()
+	s.markReady()
+
 	srvErr := make(chan error, 1)
 	go func() {
-		s.logger.Info("Starting server...")
-		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Info("Starting server...", "addr", s.addr.String(), "tls", s.tls != nil, "h2c", s.h2c && s.tls == nil)
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			srvErr <- err
 		}
 	}()
@@ -67,14 +197,9 @@ func (s *Server) Start(callerCtx context.Context) error {
 	// - received an error during server startup
 	select {
 	case <-ctx.Done():
-		shutdownCtx, shutdown := context.WithTimeout(context.Background(), 10*time.Second)
-		defer shutdown()
-
-		if err := s.srv.Shutdown(shutdownCtx); err != nil {
-			s.logger.Error("Error shutting down the HTTP server", "error", err)
-			return err
-		}
-		return nil
+		return s.shutdown()
+	case <-s.stop:
+		return s.shutdown()
 	case err := <-srvErr:
 		if err != nil {
 			s.logger.Error("Error starting the HTTP server", "error", err)
@@ -84,3 +209,36 @@ func (s *Server) Start(callerCtx context.Context) error {
 
 	return nil
 }
+
+// Stop shuts the server down gracefully and waits until Start has returned
+// or ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	s.runMu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+	started := s.started
+	s.runMu.Unlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// begin marks the server as started unless it was already stopped.
+func (s *Server) begin() bool {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.started = true
+	return true
+}
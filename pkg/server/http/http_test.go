@@ -43,9 +43,8 @@ func TestServer_Shutdown_Gracefully(t *testing.T) {
 
 	go func() {
 		time.Sleep(1 * time.Second)
-		// Simulate receiving an interrupt signal
-		p, _ := os.FindProcess(os.Getpid())
-		_ = p.Signal(os.Interrupt)
+		// Simulate the runner stopping the server on a signal
+		_ = server.Stop(context.Background())
 	}()
 
 	err := server.Start(ctx)
@@ -67,6 +66,15 @@ func TestServer_Start_ErrorOnStartup(t *testing.T) {
 	if err == nil {
 		t.Error("expected error on startup, got nil")
 	}
+
+	select {
+	case <-server.Ready():
+	default:
+		t.Fatal("Ready() was not closed after a failed start")
+	}
+	if server.Addr() != nil {
+		t.Errorf("Addr() = %v, want nil after a failed start", server.Addr())
+	}
 }
 
 func TestServer_StartupPortAlreadyInUse(t *testing.T) {
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFdsStart is the first file descriptor systemd passes sockets on.
+const listenFdsStart = 3
+
+var ErrNoSystemdSocket = errors.New("no socket passed by systemd")
+
+// WithListener serves on l instead of listening on host and port. The
+// server takes ownership of l and closes it on shutdown.
+func WithListener(l net.Listener) Option {
+	return func(s *Server) {
+		s.listener = l
+	}
+}
+
+// WithUnixSocket listens on a Unix domain socket at path instead of host
+// and port. A socket left behind at path is replaced. A mode other than 0
+// is applied to the socket file, which is how access to it is controlled.
+func WithUnixSocket(path string, mode os.FileMode) Option {
+	return func(s *Server) {
+		s.socketPath = path
+		s.socketMode = mode
+	}
+}
+
+// WithH2C serves HTTP/2 without TLS, for clients such as sidecar proxies
+// that speak it with prior knowledge. It has no effect together with TLS,
+// which negotiates HTTP/2 on its own.
+func WithH2C() Option {
+	return func(s *Server) {
+		s.h2c = true
+	}
+}
+
+// SystemdListener returns a socket passed through systemd socket
+// activation. name selects the socket by its FileDescriptorName; an empty
+// name selects the first one.
+func SystemdListener(name string) (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, ErrNoSystemdSocket
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, ErrNoSystemdSocket
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	for i := 0; i < count; i++ {
+		fdName := ""
+		if i < len(names) {
+			fdName = names[i]
+		}
+		if name != "" && fdName != name {
+			continue
+		}
+
+		f := os.NewFile(uintptr(listenFdsStart+i), fdName)
+		// FileListener works on a duplicate, so the original can go
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		return l, err
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNoSystemdSocket, name)
+}
+
+// listen opens the listener selected by the options.
+func (s *Server) listen() (net.Listener, error) {
+	if s.listener != nil {
+		return s.listener, nil
+	}
+	if s.socketPath == "" {
+		return net.Listen("tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+	}
+
+	// a socket left by a previous run would make listening fail, anything
+	// else at path is left alone so that it fails loudly
+	if info, err := os.Lstat(s.socketPath); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		if err := os.Remove(s.socketPath); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.socketMode != 0 {
+		if err := os.Chmod(s.socketPath, s.socketMode); err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Ready is closed once the server is listening, or once Start returns
+// without ever listening. Addr tells the two apart.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+func (s *Server) markReady() {
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
+// Addr returns the address the server listens on, which tells the actual
+// port when listening on port 0. It is nil until Ready is closed, and stays
+// nil when the server failed to start.
+func (s *Server) Addr() net.Addr {
+	select {
+	case <-s.ready:
+		return s.addr
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/http2"
+)
+
+// startServer runs a server until the test ends and waits for it to listen.
+func startServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	mux := chi.NewRouter()
+	mux.Get("/proto", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	})
+	server := NewServer(mux, slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	})
+
+	<-server.Ready()
+	if server.Addr() == nil {
+		// the cleanup reports the error Start returned
+		t.Fatal("server did not start listening")
+	}
+	return server
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestServer_EphemeralPort(t *testing.T) {
+	server := startServer(t, WithHost("127.0.0.1"), WithPort(0))
+
+	addr := server.Addr().(*net.TCPAddr)
+	if addr.Port == 0 {
+		t.Fatalf("Addr() = %s, want the bound port", addr)
+	}
+	if got := get(t, http.DefaultClient, "http://"+addr.String()+"/proto"); got != "HTTP/1.1" {
+		t.Errorf("protocol = %q, want HTTP/1.1", got)
+	}
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.sock")
+	server := startServer(t, WithUnixSocket(path, 0o600))
+
+	if server.Addr().String() != path {
+		t.Errorf("Addr() = %s, want %s", server.Addr(), path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("socket not created: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	if got := get(t, client, "http://unix/proto"); got != "HTTP/1.1" {
+		t.Errorf("protocol = %q, want HTTP/1.1", got)
+	}
+}
+
+func TestServer_H2C(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := startServer(t, WithListener(l), WithH2C())
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	if got := get(t, client, "http://"+server.Addr().String()+"/proto"); got != "HTTP/2.0" {
+		t.Errorf("protocol = %q, want HTTP/2.0", got)
+	}
+}
+
+func TestSystemdListener_NotActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+
+	if _, err := SystemdListener(""); !errors.Is(err, ErrNoSystemdSocket) {
+		t.Errorf("SystemdListener() error = %v, want %v", err, ErrNoSystemdSocket)
+	}
+}
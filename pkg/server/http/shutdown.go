@@ -0,0 +1,47 @@
+package http
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long in-flight requests get to finish once
+// shutdown begins.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// WithShutdownTimeout sets the grace period in-flight requests get to
+// finish. Connections still open after it are closed.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithShutdownDelay keeps serving for d after shutdown begins, once the
+// WithOnShutdown functions have run. It gives load balancers time to notice
+// the failing readiness check and stop sending new requests.
+func WithShutdownDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownDelay = d
+	}
+}
+
+// shutdown drains the server.
+func (s *Server) shutdown() error {
+	s.logger.Info("Shutting down the HTTP server", "delay", s.shutdownDelay.String(), "timeout", s.shutdownTimeout.String())
+	for _, fn := range s.onShutdown {
+		fn()
+	}
+	if s.shutdownDelay > 0 {
+		time.Sleep(s.shutdownDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.logger.Error("Error shutting down the HTTP server, closing the remaining connections", "error", err)
+		_ = s.srv.Close()
+		return err
+	}
+	return nil
+}
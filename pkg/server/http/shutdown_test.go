@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestServer_ShutdownDrains(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	mux := chi.NewRouter()
+	mux.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		record("request")
+		_, _ = io.WriteString(w, "done")
+	})
+	mux.Get("/fast", func(w http.ResponseWriter, r *http.Request) {})
+	draining := make(chan struct{})
+
+	server := NewServer(mux, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithHost("127.0.0.1"),
+		WithPort(0),
+		WithShutdownDelay(200*time.Millisecond),
+		WithOnShutdown(func() { close(draining) }),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- server.Start(ctx) }()
+	<-server.Ready()
+	url := "http://" + server.Addr().String()
+
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+	<-started
+
+	cancel()
+	<-draining
+	// new requests are still served during the delay
+	resp, err := http.Get(url + "/fast")
+	if err != nil {
+		t.Fatalf("request during the shutdown delay failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	close(release)
+	if body := <-slow; body != "done" {
+		t.Errorf("in-flight request = %q, want done", body)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+	record("stopped")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"request", "stopped"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	mux := chi.NewRouter()
+	mux.Get("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	server := NewServer(mux, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithHost("127.0.0.1"),
+		WithPort(0),
+		WithShutdownTimeout(100*time.Millisecond),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- server.Start(ctx) }()
+	<-server.Ready()
+
+	go func() {
+		resp, err := http.Get("http://" + server.Addr().String() + "/stuck")
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+
+	err := <-done
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Start() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestServer_Stop(t *testing.T) {
+	server := NewServer(chi.NewRouter(), slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithHost("127.0.0.1"),
+		WithPort(0),
+	)
+
+	done := make(chan error, 1)
+	go func() { done <- server.Start(context.Background()) }()
+	<-server.Ready()
+
+	if err := server.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if conn, err := net.Dial("tcp", server.Addr().String()); err == nil {
+		_ = conn.Close()
+		t.Error("Stop() returned before shutdown finished")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+	// stopping twice is harmless
+	if err := server.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
+}
+
+func TestServer_StopBeforeStart(t *testing.T) {
+	server := NewServer(chi.NewRouter(), slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithHost("127.0.0.1"),
+		WithPort(0),
+	)
+
+	if err := server.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Start(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start() served after Stop")
+	}
+	select {
+	case <-server.Ready():
+	default:
+		t.Error("Ready() was not closed")
+	}
+	if server.Addr() != nil {
+		t.Errorf("Addr() = %v, want nil after Stop before Start", server.Addr())
+	}
+}
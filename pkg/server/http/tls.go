@@ -0,0 +1,289 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certCheckInterval is how often, at most, certificate files are checked
+// for changes. Checks happen during handshakes, so an idle server does not
+// poll the disk.
+const certCheckInterval = 5 * time.Second
+
+// TLSConfig enables HTTPS. MinVersion defaults to TLS 1.2. CipherSuites
+// only restricts TLS 1.2 and below, since Go always picks TLS 1.3 suites
+// itself. Setting ClientCAFile turns on mutual TLS, with ClientAuth
+// defaulting to tls.RequireAndVerifyClientCert; as tls.NoClientCert is the
+// zero value it cannot be asked for alongside ClientCAFile, leave the file
+// out instead.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	MinVersion   uint16
+	CipherSuites []uint16
+	ClientCAFile string
+	ClientAuth   tls.ClientAuthType
+}
+
+// WithTLS serves HTTPS. Certificate, key and client CA files are reloaded
+// when they change on disk, so rotating them needs no restart.
+func WithTLS(conf TLSConfig) Option {
+	return func(s *Server) {
+		s.tls = &conf
+	}
+}
+
+// ParseTLSVersion turns "1.0" to "1.3" into the tls package constant.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(version), "tls") {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version %q", version)
+}
+
+// ParseCipherSuites looks up cipher suites by their standard names, such as
+// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256". Insecure suites are rejected.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	known := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ParseClientAuth turns "none", "request", "require", "verify_if_given" or
+// "require_and_verify" into the tls package constant.
+func ParseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", "require_and_verify":
+		return tls.RequireAndVerifyClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	}
+	return 0, fmt.Errorf("unknown client auth mode %q", mode)
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certReloader serves the TLS configuration built from the files in conf,
+// rebuilding it when any of them changes. A failed reload keeps the
+// previous configuration.
+type certReloader struct {
+	conf   TLSConfig
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	config    *tls.Config
+	stamps    map[string]fileStamp
+	lastCheck time.Time
+}
+
+func newCertReloader(conf TLSConfig, logger *slog.Logger) (*certReloader, error) {
+	if conf.MinVersion == 0 {
+		conf.MinVersion = tls.VersionTLS12
+	}
+	if conf.ClientCAFile != "" && conf.ClientAuth == tls.NoClientCert {
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	c := &certReloader{conf: conf, logger: logger, now: time.Now}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) files() []string {
+	files := []string{c.conf.CertFile, c.conf.KeyFile}
+	if c.conf.ClientCAFile != "" {
+		files = append(files, c.conf.ClientCAFile)
+	}
+	return files
+}
+
+func (c *certReloader) stat() (map[string]fileStamp, error) {
+	stamps := map[string]fileStamp{}
+	for _, file := range c.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+func (c *certReloader) load() error {
+	stamps, err := c.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.conf.CertFile, c.conf.KeyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   c.conf.MinVersion,
+		CipherSuites: c.conf.CipherSuites,
+		ClientAuth:   c.conf.ClientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if c.conf.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.conf.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("loading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("loading client CA: no certificates found")
+		}
+		config.ClientCAs = pool
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = config
+	c.stamps = stamps
+	return nil
+}
+
+// maybeReload reloads the files if they changed since the last check.
+func (c *certReloader) maybeReload() {
+	now := c.now()
+	c.mu.Lock()
+	if now.Sub(c.lastCheck) < certCheckInterval {
+		c.mu.Unlock()
+		return
+	}
+	c.lastCheck = now
+	previous := c.stamps
+	c.mu.Unlock()
+
+	stamps, err := c.stat()
+	if err != nil {
+		c.logger.Error("Error checking TLS files", "error", err)
+		return
+	}
+	changed := false
+	for file, stamp := range stamps {
+		if previous[file] != stamp {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if err := c.load(); err != nil {
+		c.logger.Error("Error reloading TLS files, keeping the previous ones", "error", err)
+		return
+	}
+	c.logger.Info("Reloaded TLS files")
+}
+
+func (c *certReloader) current() *tls.Config {
+	c.maybeReload()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// tlsConfig is what the http.Server is given. Every handshake picks up the
+// latest configuration.
+func (c *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: c.conf.MinVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return c.current(), nil
+		},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &c.current().Certificates[0], nil
+		},
+	}
+}
+
+// ClientIdentity describes the certificate a client authenticated with
+// over mutual TLS.
+type ClientIdentity struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	URIs         []string
+	SerialNumber string
+	Certificate  *x509.Certificate
+}
+
+type ctxKey string
+
+const clientIdentityKey ctxKey = "client_identity"
+
+// ClientIdentityFromContext returns the verified client certificate of the
+// request. ok is false for plain HTTP and when no certificate was verified.
+func ClientIdentityFromContext(ctx context.Context) (identity *ClientIdentity, ok bool) {
+	identity, ok = ctx.Value(clientIdentityKey).(*ClientIdentity)
+	return identity, ok
+}
+
+// withClientIdentity exposes the verified client certificate to handlers.
+func withClientIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		leaf := r.TLS.VerifiedChains[0][0]
+		identity := &ClientIdentity{
+			CommonName:   leaf.Subject.CommonName,
+			Organization: leaf.Subject.Organization,
+			DNSNames:     leaf.DNSNames,
+			SerialNumber: leaf.SerialNumber.String(),
+			Certificate:  leaf,
+		}
+		for _, uri := range leaf.URIs {
+			identity.URIs = append(identity.URIs, uri.String())
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIdentityKey, identity)))
+	})
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, serial int64, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template.SerialNumber = big.NewInt(serial)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if keyFile == "" {
+		return
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt")
+
+	ca := newTestCert(t, 1, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	serverTemplate := func() *x509.Certificate {
+		return &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "server"},
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+	}
+	client := newTestCert(t, 3, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "billing", Organization: []string{"payments"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	ca.write(t, caFile, "")
+	newTestCert(t, 2, serverTemplate(), ca).write(t, certFile, keyFile)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mux := chi.NewRouter()
+	mux.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := ClientIdentityFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, identity.CommonName+"/"+identity.Organization[0])
+	})
+	server := NewServer(mux, logger, WithTLS(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}))
+
+	reloader, err := newCertReloader(*server.tls, logger)
+	if err != nil {
+		t.Fatalf("newCertReloader() error = %v", err)
+	}
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	ts := httptest.NewUnstartedServer(server.handler())
+	ts.TLS = reloader.tlsConfig()
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+			DisableKeepAlives: true,
+		}}
+	}
+
+	resp, err := newClient(client.tlsCertificate()).Get(ts.URL + "/whoami")
+	if err != nil {
+		t.Fatalf("request with client certificate failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "billing/payments" {
+		t.Errorf("client identity = %q, want billing/payments", body)
+	}
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 2 {
+		t.Errorf("server certificate serial = %d, want 2", serial)
+	}
+
+	if _, err := newClient().Get(ts.URL + "/whoami"); err == nil {
+		t.Errorf("request without client certificate succeeded, want handshake failure")
+	}
+
+	// rotate the server certificate on disk
+	newTestCert(t, 4, serverTemplate(), ca).write(t, certFile, keyFile)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, later, later); err != nil {
+		t.Fatalf("failed to touch certificate: %v", err)
+	}
+	now = now.Add(certCheckInterval)
+
+	resp, err = newClient(client.tlsCertificate()).Get(ts.URL + "/whoami")
+	if err != nil {
+		t.Fatalf("request after rotation failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 4 {
+		t.Errorf("server certificate serial after rotation = %d, want 4", serial)
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/giortzisg/go-boilerplate/pkg/telemetry"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestServer_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	telemetry.SetGlobal(tp)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mux := chi.NewRouter()
+	mux.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tp.Tracer("test").Start(r.Context(), "userService.GetByID")
+		span.End()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := NewServer(mux, logger, WithTracerProvider(tp))
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	server.handler().ServeHTTP(w, r)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	child, root := spans[0], spans[1]
+
+	if root.Name != "GET" {
+		t.Errorf("expected server span to be named after the method, got %q", root.Name)
+	}
+	if root.SpanKind != trace.SpanKindServer {
+		t.Errorf("expected server span kind, got %v", root.SpanKind)
+	}
+	if root.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected inbound trace to be continued, got trace %s", root.SpanContext.TraceID())
+	}
+	if root.Parent.SpanID().String() != "00f067aa0ba902b7" || !root.Parent.IsRemote() {
+		t.Errorf("expected server span parent to be the remote caller, got %s", root.Parent.SpanID())
+	}
+	if child.Parent.SpanID() != root.SpanContext.SpanID() {
+		t.Errorf("expected handler span to be a child of the server span")
+	}
+
+	echoed := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(w.Header()))
+	if sc := trace.SpanContextFromContext(echoed); sc.SpanID() != root.SpanContext.SpanID() {
+		t.Errorf("expected response traceparent to carry the server span, got %s", w.Header().Get("traceparent"))
+	}
+}
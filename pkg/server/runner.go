@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultStopTimeout bounds how long the servers of a Runner get to stop.
+const DefaultStopTimeout = 30 * time.Second
+
+type namedServer struct {
+	name   string
+	server Server
+}
+
+type cleanup struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// Runner runs a group of servers as one application. The servers start
+// together, and when one of them fails, the context is done or the process
+// receives SIGINT or SIGTERM, all of them are stopped.
+type Runner struct {
+	logger      *slog.Logger
+	servers     []namedServer
+	cleanups    []cleanup
+	stopTimeout time.Duration
+}
+
+type Option func(r *Runner)
+
+func NewRunner(logger *slog.Logger, args ...Option) *Runner {
+	r := &Runner{
+		logger:      logger,
+		stopTimeout: DefaultStopTimeout,
+	}
+
+	for _, opts := range args {
+		opts(r)
+	}
+	return r
+}
+
+// WithServer adds a server to run. name identifies it in logs and errors.
+func WithServer(name string, s Server) Option {
+	return func(r *Runner) {
+		r.servers = append(r.servers, namedServer{name: name, server: s})
+	}
+}
+
+// WithCleanup registers fn to run once every server has stopped, such as
+// closing the database pool or flushing telemetry that the servers use.
+// Cleanups run one after another in the order they were registered, and a
+// failing one does not keep the next ones from running.
+func WithCleanup(name string, fn func(context.Context) error) Option {
+	return func(r *Runner) {
+		r.cleanups = append(r.cleanups, cleanup{name: name, fn: fn})
+	}
+}
+
+// WithStopTimeout sets how long the servers get to stop once stopping
+// begins. It should cover their own grace periods.
+func WithStopTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.stopTimeout = d
+	}
+}
+
+// Run starts every server and blocks until all of them have returned, then
+// runs the cleanups. It returns the errors of all servers that failed to
+// run or to stop, joined and prefixed with the server name, along with
+// those of the cleanups. A second signal while stopping kills the process
+// right away.
+func (r *Runner) Run(callerCtx context.Context) error {
+	signalCtx, stop := signal.NotifyContext(callerCtx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancelCause(signalCtx)
+	defer cancel(nil)
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	addErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+
+	for _, s := range r.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.server.Start(ctx); err != nil {
+				err = fmt.Errorf("%s: %w", s.name, err)
+				addErr(err)
+				cancel(err)
+			}
+		}()
+	}
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		// every server returned on its own
+		return errors.Join(append(errs, r.cleanup()...)...)
+	case <-ctx.Done():
+	}
+	// restore the default handling so a second signal is not ignored
+	stop()
+	r.logger.Info("Stopping servers", "reason", context.Cause(ctx).Error())
+
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), r.stopTimeout)
+	defer cancelStop()
+	var stopping sync.WaitGroup
+	for _, s := range r.servers {
+		stopping.Add(1)
+		go func() {
+			defer stopping.Done()
+			if err := s.server.Stop(stopCtx); err != nil {
+				r.logger.Error("Error stopping server", "server", s.name, "error", err)
+				addErr(fmt.Errorf("%s: stopping: %w", s.name, err))
+			}
+		}()
+	}
+	stopping.Wait()
+
+	select {
+	case <-finished:
+	case <-stopCtx.Done():
+		addErr(fmt.Errorf("servers did not stop within %s", r.stopTimeout))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return errors.Join(append(errs, r.cleanup()...)...)
+}
+
+// cleanup runs the cleanups, giving them the stop timeout between them.
+func (r *Runner) cleanup() []error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.stopTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, c := range r.cleanups {
+		if err := c.fn(ctx); err != nil {
+			r.logger.Error("Error running cleanup", "cleanup", c.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errs
+}
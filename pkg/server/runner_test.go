@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeServer runs until ctx is done or Stop is called, or fails with err
+// after delay when err is set.
+type fakeServer struct {
+	err     error
+	delay   time.Duration
+	stopErr error
+	stuck   bool
+
+	stop    chan struct{}
+	stopped atomic.Bool
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{stop: make(chan struct{})}
+}
+
+func (f *fakeServer) Start(ctx context.Context) error {
+	if f.err != nil {
+		time.Sleep(f.delay)
+		return f.err
+	}
+	if f.stuck {
+		select {}
+	}
+	select {
+	case <-ctx.Done():
+	case <-f.stop:
+	}
+	return nil
+}
+
+func (f *fakeServer) Stop(ctx context.Context) error {
+	if f.stopped.CompareAndSwap(false, true) {
+		close(f.stop)
+	}
+	return f.stopErr
+}
+
+func newTestRunner(opts ...Option) *Runner {
+	return NewRunner(slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+}
+
+func TestRunner_StopsAllWhenOneFails(t *testing.T) {
+	errBind := errors.New("address already in use")
+	failing := newFakeServer()
+	failing.err = errBind
+	failing.delay = 50 * time.Millisecond
+	healthy, other := newFakeServer(), newFakeServer()
+
+	err := newTestRunner(
+		WithServer("http", failing),
+		WithServer("admin", healthy),
+		WithServer("worker", other),
+	).Run(context.Background())
+
+	if !errors.Is(err, errBind) {
+		t.Fatalf("Run() error = %v, want %v", err, errBind)
+	}
+	if !strings.Contains(err.Error(), "http: ") {
+		t.Errorf("Run() error = %q, want it prefixed with the server name", err)
+	}
+	if !healthy.stopped.Load() || !other.stopped.Load() {
+		t.Error("healthy servers were not stopped")
+	}
+}
+
+func TestRunner_AggregatesErrors(t *testing.T) {
+	errStart, errStop := errors.New("start failed"), errors.New("stop failed")
+	failing := newFakeServer()
+	failing.err = errStart
+	stopFailing := newFakeServer()
+	stopFailing.stopErr = errStop
+
+	err := newTestRunner(WithServer("a", failing), WithServer("b", stopFailing)).Run(context.Background())
+
+	if !errors.Is(err, errStart) || !errors.Is(err, errStop) {
+		t.Errorf("Run() error = %v, want both %v and %v", err, errStart, errStop)
+	}
+}
+
+func TestRunner_StopsOnContextDone(t *testing.T) {
+	a, b := newFakeServer(), newFakeServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if err := newTestRunner(WithServer("a", a), WithServer("b", b)).Run(ctx); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	if !a.stopped.Load() || !b.stopped.Load() {
+		t.Error("servers were not stopped")
+	}
+}
+
+func TestRunner_StopsOnSIGTERM(t *testing.T) {
+	a, b := newFakeServer(), newFakeServer()
+	time.AfterFunc(50*time.Millisecond, func() {
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(syscall.SIGTERM)
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- newTestRunner(WithServer("a", a), WithServer("b", b)).Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("servers did not stop on SIGTERM")
+	}
+	if !a.stopped.Load() || !b.stopped.Load() {
+		t.Error("servers were not stopped")
+	}
+}
+
+func TestRunner_ReturnsWhenAllFinish(t *testing.T) {
+	job := newFakeServer()
+	close(job.stop)
+	job.stopped.Store(true)
+
+	if err := newTestRunner(WithServer("job", job)).Run(context.Background()); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestRunner_StopTimeout(t *testing.T) {
+	stuck := newFakeServer()
+	stuck.stuck = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := newTestRunner(WithServer("stuck", stuck), WithStopTimeout(50*time.Millisecond)).Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "did not stop") {
+		t.Errorf("Run() error = %v, want a stop timeout", err)
+	}
+}
+
+func TestRunner_CleanupAfterServersStop(t *testing.T) {
+	a, b := newFakeServer(), newFakeServer()
+	errFlush := errors.New("flush failed")
+	var calls []string
+	cleanup := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			if !a.stopped.Load() || !b.stopped.Load() {
+				t.Errorf("cleanup %s ran before the servers stopped", name)
+			}
+			calls = append(calls, name)
+			return err
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := newTestRunner(
+		WithServer("a", a),
+		WithServer("b", b),
+		WithCleanup("tracing", cleanup("tracing", errFlush)),
+		WithCleanup("database", cleanup("database", nil)),
+	).Run(ctx)
+
+	if !errors.Is(err, errFlush) {
+		t.Errorf("Run() error = %v, want %v", err, errFlush)
+	}
+	if len(calls) != 2 || calls[0] != "tracing" || calls[1] != "database" {
+		t.Errorf("cleanups ran as %v, want [tracing database]", calls)
+	}
+}
@@ -2,6 +2,11 @@ package server
 
 import "context"
 
+// Server is a long running part of a binary, such as an HTTP server or a
+// background worker. Start blocks until the server stops, which happens
+// when ctx is done or Stop is called. Stop asks the server to stop
+// gracefully and waits until it has, or until ctx is done.
 type Server interface {
 	Start(context.Context) error
+	Stop(context.Context) error
 }
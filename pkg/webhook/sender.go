@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Defaults of a Sender.
+const (
+	DefaultTimeout   = 10 * time.Second
+	DefaultUserAgent = "go-boilerplate-webhooks/1"
+	// MaxResponseBody is how much of a response body is kept for the
+	// delivery history.
+	MaxResponseBody = 4 << 10
+)
+
+var (
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+	// ErrNonPublicAddress keeps webhooks from reaching loopback, private
+	// and other internal addresses, such as a cloud metadata service.
+	ErrNonPublicAddress = errors.New("webhook destination is not a public address")
+)
+
+// nonPublicRanges are the special purpose ranges IsPublic rejects on top of
+// those the netip predicates cover.
+var nonPublicRanges = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// IsPublic reports whether addr is a globally routable unicast address, so
+// neither loopback, private, link-local, unspecified nor multicast.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicRanges {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Request is a webhook to send. ID identifies the delivery and stays the
+// same across retries, so receivers can drop duplicates.
+type Request struct {
+	ID     string
+	URL    string
+	Secret string
+	Body   []byte
+}
+
+// Response is what the endpoint answered. Body is cut at MaxResponseBody.
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+type Option func(s *Sender)
+
+// WithHTTPClient sends with client. The default client does not follow
+// redirects, so that a webhook only ever reaches the registered URL, and
+// refuses to connect to addresses that are not public; client has to take
+// care of both itself.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Sender) {
+		s.client = client
+	}
+}
+
+// WithTimeout bounds how long a single attempt may take.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Sender) {
+		s.timeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header of webhook requests.
+func WithUserAgent(userAgent string) Option {
+	return func(s *Sender) {
+		s.userAgent = userAgent
+	}
+}
+
+// Sender posts signed webhook requests.
+type Sender struct {
+	client    *http.Client
+	timeout   time.Duration
+	userAgent string
+	now       func() time.Time
+	// allowed reports whether the default client may connect to addr
+	allowed func(addr netip.Addr) bool
+}
+
+func NewSender(opts ...Option) *Sender {
+	s := &Sender{
+		timeout:   DefaultTimeout,
+		userAgent: DefaultUserAgent,
+		now:       time.Now,
+		allowed:   IsPublic,
+	}
+	// the address is checked once resolved, so a host cannot pass
+	// registration and later resolve somewhere internal; without a proxy
+	// the address dialled is the destination itself
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: s.control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	s.client = &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Sender) control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !s.allowed(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// Send posts req as JSON, signed with its secret. Any status other than
+// 2xx fails with ErrUnexpectedStatus. The Response is returned whenever the
+// endpoint answered, even with an error status.
+func (s *Sender) Send(ctx context.Context, req Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := s.now()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", s.userAgent)
+	httpReq.Header.Set(IDHeader, req.ID)
+	httpReq.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	httpReq.Header.Set(SignatureHeader, Sign(req.Secret, timestamp, req.Body))
+
+	httpResp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(httpResp.Body, MaxResponseBody))
+	// drain a little more so the connection can be reused
+	_, _ = io.CopyN(io.Discard, httpResp.Body, MaxResponseBody)
+	resp := &Response{
+		StatusCode: httpResp.StatusCode,
+		Body:       body,
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return resp, fmt.Errorf("%w: %d", ErrUnexpectedStatus, httpResp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSender returns a Sender that may reach the loopback test servers.
+func newTestSender(opts ...Option) *Sender {
+	s := NewSender(opts...)
+	s.allowed = func(netip.Addr) bool { return true }
+	return s
+}
+
+func TestSender_Send(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"evt-1","type":"user.created"}`)
+
+	var received http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		if err := Verify(secret, r.Header, payload, 0, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		received = r.Header
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer receiver.Close()
+
+	resp, err := newTestSender().Send(context.Background(), Request{ID: "dlv-1", URL: receiver.URL, Secret: secret, Body: body})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "ok" {
+		t.Errorf("Send() = %d %q, want 200 ok", resp.StatusCode, resp.Body)
+	}
+	if got := received.Get(IDHeader); got != "dlv-1" {
+		t.Errorf("%s = %q, want dlv-1", IDHeader, got)
+	}
+	if got := received.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	_, err = newTestSender().Send(context.Background(), Request{ID: "dlv-1", URL: receiver.URL, Secret: "whsec_wrong", Body: body})
+	if !errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("Send() with the wrong secret error = %v, want %v", err, ErrUnexpectedStatus)
+	}
+}
+
+func TestSender_Failures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, strings.Repeat("x", 2*MaxResponseBody))
+		case "/redirect":
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}))
+	defer receiver.Close()
+	sender := newTestSender(WithTimeout(50 * time.Millisecond))
+
+	resp, err := sender.Send(context.Background(), Request{URL: receiver.URL + "/error"})
+	if !errors.Is(err, ErrUnexpectedStatus) || resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Send() = %v, %v, want a 500 response and %v", resp, err, ErrUnexpectedStatus)
+	}
+	if len(resp.Body) != MaxResponseBody {
+		t.Errorf("response body is %d bytes, want it cut at %d", len(resp.Body), MaxResponseBody)
+	}
+
+	resp, err = sender.Send(context.Background(), Request{URL: receiver.URL + "/redirect"})
+	if !errors.Is(err, ErrUnexpectedStatus) || resp.StatusCode != http.StatusFound {
+		t.Errorf("Send() = %v, %v, want the redirect not to be followed", resp, err)
+	}
+
+	if _, err = sender.Send(context.Background(), Request{URL: receiver.URL + "/slow"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSender_RejectsNonPublicAddresses(t *testing.T) {
+	var reached bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer receiver.Close()
+
+	_, err := NewSender().Send(context.Background(), Request{ID: "dlv-1", URL: receiver.URL, Secret: "whsec_test"})
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("Send() error = %v, want %v", err, ErrNonPublicAddress)
+	}
+	if reached {
+		t.Error("Send() reached a loopback address")
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.215.14", want: true},
+		{addr: "2606:2800:21f:cb07:6820:80da:af6b:8b2c", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "fd00::1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "0.0.0.0"},
+		{addr: "::"},
+		{addr: "100.64.0.1"},
+		{addr: "224.0.0.1"},
+		{addr: "::ffff:127.0.0.1"},
+	}
+	for _, tt := range tests {
+		if got := IsPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"evt-1"}`)
+	sent := time.Unix(1700000000, 0)
+
+	signed := func(signature string, timestamp time.Time) http.Header {
+		header := http.Header{}
+		header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+		header.Set(SignatureHeader, signature)
+		return header
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{
+			name:   "valid",
+			header: signed(Sign(secret, sent, body), sent),
+			body:   body,
+			now:    sent.Add(time.Minute),
+		},
+		{
+			name:   "one of several signatures matches",
+			header: signed(Sign("whsec_old", sent, body)+" "+Sign(secret, sent, body), sent),
+			body:   body,
+			now:    sent,
+		},
+		{
+			name:   "tampered body",
+			header: signed(Sign(secret, sent, body), sent),
+			body:   []byte(`{"id":"evt-2"}`),
+			now:    sent,
+			want:   ErrInvalidSignature,
+		},
+		{
+			name:   "wrong secret",
+			header: signed(Sign("whsec_other", sent, body), sent),
+			body:   body,
+			now:    sent,
+			want:   ErrInvalidSignature,
+		},
+		{
+			name:   "timestamp changed after signing",
+			header: signed(Sign(secret, sent, body), sent.Add(time.Second)),
+			body:   body,
+			now:    sent,
+			want:   ErrInvalidSignature,
+		},
+		{
+			name:   "replayed too late",
+			header: signed(Sign(secret, sent, body), sent),
+			body:   body,
+			now:    sent.Add(DefaultTolerance + time.Second),
+			want:   ErrTimestampExpired,
+		},
+		{
+			name:   "unknown signature version",
+			header: signed(strings.Replace(Sign(secret, sent, body), "v1=", "v0=", 1), sent),
+			body:   body,
+			now:    sent,
+			want:   ErrInvalidSignature,
+		},
+		{
+			name:   "unsigned",
+			header: http.Header{},
+			body:   body,
+			now:    sent,
+			want:   ErrMissingSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(secret, tt.header, tt.body, 0, tt.now); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret() error = %v", err)
+	}
+	b, _ := NewSecret()
+	if !strings.HasPrefix(a, "whsec_") || a == b {
+		t.Errorf("NewSecret() = %q and %q, want distinct whsec_ secrets", a, b)
+	}
+}